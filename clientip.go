@@ -0,0 +1,129 @@
+package logger
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// GoogleLoadBalancerPrefixes returns the address ranges Google Cloud Load Balancers and health checks
+// connect to backends from, for use with WithTrustedProxies. The load balancer appends the client address
+// and then the IP of its forwarding rule to X-Forwarded-For, so also skip the forwarding rule with
+// WithTrustedHops(1), or add its IP to the trusted proxies.
+func GoogleLoadBalancerPrefixes() []netip.Prefix {
+	return []netip.Prefix{
+		netip.MustParsePrefix("35.191.0.0/16"),
+		netip.MustParsePrefix("130.211.0.0/22"),
+	}
+}
+
+// defaultTrustedProxies returns the loopback, private and link-local ranges, which are trusted
+// when no proxies are configured
+func defaultTrustedProxies() []netip.Prefix {
+	return []netip.Prefix{
+		netip.MustParsePrefix("127.0.0.0/8"),
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("172.16.0.0/12"),
+		netip.MustParsePrefix("192.168.0.0/16"),
+		netip.MustParsePrefix("169.254.0.0/16"),
+		netip.MustParsePrefix("::1/128"),
+		netip.MustParsePrefix("fc00::/7"),
+		netip.MustParsePrefix("fe80::/10"),
+	}
+}
+
+// clientIP returns the IP address of the client that issued the request. If the connection is from
+// a trusted proxy, the addresses in the Forwarded, X-Forwarded-For or X-Real-IP header are walked from
+// the right, and the first that is not a trusted proxy is the client. The last trustedHops addresses
+// are trusted whatever they are.
+func (c config) clientIP(r *http.Request) string {
+	trusted := c.trustedProxies
+	if trusted == nil {
+		trusted = defaultTrustedProxies()
+	}
+
+	addr, ok := parseAddr(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr
+	}
+	if !isTrusted(trusted, addr) {
+		return addr.String()
+	}
+
+	hops := forwardedHops(r.Header)
+	// addr is the remote address, or one of the last trustedHops hops, while i >= last
+	last := len(hops) - 1 - c.trustedHops
+	for i := len(hops) - 1; i >= 0 && (i >= last || isTrusted(trusted, addr)); i-- {
+		next, ok := parseAddr(hops[i])
+		if !ok {
+			// The client is unknown, so report the closest proxy
+			break
+		}
+		addr = next
+	}
+
+	return addr.String()
+}
+
+// forwardedHops returns the addresses of the clients and proxies that forwarded the request, in order.
+// Only the first of the Forwarded, X-Forwarded-For or X-Real-IP headers found is used.
+func forwardedHops(h http.Header) []string {
+	if values := h.Values("Forwarded"); len(values) != 0 {
+		var hops []string
+		for _, v := range values {
+			for _, elem := range strings.Split(v, ",") {
+				for _, pair := range strings.Split(elem, ";") {
+					key, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+					if strings.EqualFold(key, "for") {
+						hops = append(hops, strings.Trim(value, `"`))
+					}
+				}
+			}
+		}
+
+		return hops
+	}
+
+	if values := h.Values("X-Forwarded-For"); len(values) != 0 {
+		var hops []string
+		for _, v := range values {
+			for _, hop := range strings.Split(v, ",") {
+				hops = append(hops, strings.TrimSpace(hop))
+			}
+		}
+
+		return hops
+	}
+
+	if v := h.Get("X-Real-IP"); v != "" {
+		return []string{strings.TrimSpace(v)}
+	}
+
+	return nil
+}
+
+// parseAddr parses an IP address with an optional port, such as "192.0.2.1", "192.0.2.1:80",
+// "2001:db8::1" or "[2001:db8::1]:80"
+func parseAddr(s string) (netip.Addr, bool) {
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return ap.Addr().Unmap(), true
+	}
+
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
+
+// isTrusted reports if addr is in one of the trusted prefixes
+func isTrusted(trusted []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
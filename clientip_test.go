@@ -0,0 +1,162 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func Test_config_clientIP(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		trusted     []netip.Prefix
+		trustedHops int
+		remoteAddr  string
+		headers     map[string][]string
+		want        string
+	}{
+		{
+			name:       "remote addr",
+			remoteAddr: "203.0.113.7:52100",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "untrusted remote addr ignores headers",
+			remoteAddr: "203.0.113.7:52100",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1"}},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "x-forwarded-for",
+			remoteAddr: "10.0.0.2:52100",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1, 10.0.0.1"}},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "x-forwarded-for spoofed",
+			remoteAddr: "10.0.0.2:52100",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.2.3.4, 198.51.100.1", "10.0.0.1"}},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "x-forwarded-for all trusted",
+			remoteAddr: "10.0.0.2:52100",
+			headers:    map[string][]string{"X-Forwarded-For": {"192.168.1.5, 10.0.0.1"}},
+			want:       "192.168.1.5",
+		},
+		{
+			name:       "x-forwarded-for invalid hop",
+			remoteAddr: "10.0.0.2:52100",
+			headers:    map[string][]string{"X-Forwarded-For": {"unknown, 10.0.0.1"}},
+			want:       "10.0.0.1",
+		},
+		{
+			name:        "google load balancer",
+			trusted:     GoogleLoadBalancerPrefixes(),
+			trustedHops: 1,
+			remoteAddr:  "35.191.10.10:52100",
+			headers:     map[string][]string{"X-Forwarded-For": {"198.51.100.1, 34.111.22.33"}},
+			want:        "198.51.100.1",
+		},
+		{
+			name:        "google load balancer spoofed",
+			trusted:     GoogleLoadBalancerPrefixes(),
+			trustedHops: 1,
+			remoteAddr:  "130.211.0.5:52100",
+			headers:     map[string][]string{"X-Forwarded-For": {"1.2.3.4, 198.51.100.1, 34.111.22.33"}},
+			want:        "198.51.100.1",
+		},
+		{
+			name:       "google load balancer forwarding rule trusted",
+			trusted:    append(GoogleLoadBalancerPrefixes(), netip.MustParsePrefix("34.111.22.33/32")),
+			remoteAddr: "35.191.10.10:52100",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1, 34.111.22.33"}},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "google load balancer without trusted hops",
+			trusted:    GoogleLoadBalancerPrefixes(),
+			remoteAddr: "35.191.10.10:52100",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1, 34.111.22.33"}},
+			want:       "34.111.22.33",
+		},
+		{
+			name:        "trusted hops from untrusted remote addr",
+			trustedHops: 1,
+			remoteAddr:  "203.0.113.7:52100",
+			headers:     map[string][]string{"X-Forwarded-For": {"198.51.100.1, 34.111.22.33"}},
+			want:        "203.0.113.7",
+		},
+		{
+			name:        "trusted hops more than hops",
+			trustedHops: 3,
+			remoteAddr:  "10.0.0.2:52100",
+			headers:     map[string][]string{"X-Forwarded-For": {"198.51.100.1, 34.111.22.33"}},
+			want:        "198.51.100.1",
+		},
+		{
+			name:       "x-real-ip",
+			remoteAddr: "127.0.0.1:52100",
+			headers:    map[string][]string{"X-Real-Ip": {"198.51.100.1"}},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "forwarded",
+			remoteAddr: "[::1]:52100",
+			headers: map[string][]string{
+				"Forwarded":       {`for="[2001:db8:cafe::17]:4711";proto=https, for=10.0.0.1;by=10.0.0.2`},
+				"X-Forwarded-For": {"1.2.3.4"},
+			},
+			want: "2001:db8:cafe::17",
+		},
+		{
+			name:       "forwarded ipv4 with port",
+			remoteAddr: "10.0.0.2:52100",
+			headers:    map[string][]string{"Forwarded": {"For=198.51.100.1:4711"}},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "ipv4 mapped ipv6",
+			remoteAddr: "[::ffff:10.0.0.2]:52100",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1"}},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "no trusted proxies",
+			trusted:    []netip.Prefix{},
+			remoteAddr: "10.0.0.2:52100",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1"}},
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "remote addr without port",
+			remoteAddr: "203.0.113.7",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "invalid remote addr",
+			remoteAddr: "pipe",
+			want:       "pipe",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			r.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				r.Header[k] = v
+			}
+
+			c := config{trustedProxies: tt.trusted, trustedHops: tt.trustedHops}
+			if got := c.clientIP(r); got != tt.want {
+				t.Errorf("config.clientIP() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
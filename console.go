@@ -2,27 +2,40 @@ package logger
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/logging"
+	"google.golang.org/grpc"
 )
 
 type color int
 
 const (
-	red    color = 31
-	yellow color = 33
-	blue   color = 34
-	gray   color = 37
+	red     color = 31
+	green   color = 32
+	yellow  color = 33
+	blue    color = 34
+	magenta color = 35
+	cyan    color = 36
+	gray    color = 37
 )
 
 // ConsoleExporter implements exporting to Google Cloud Logging
 type ConsoleExporter struct {
 	noColor bool
+	config
 }
 
 // NewConsoleExporter returns a configured ConsoleExporter
 func NewConsoleExporter() *ConsoleExporter {
-	return &ConsoleExporter{}
+	return &ConsoleExporter{
+		config: config{logAll: true},
+	}
 }
 
 // NoColor controls if this logger will use color to highlight log level
@@ -32,88 +45,258 @@ func (e *ConsoleExporter) NoColor(v bool) *ConsoleExporter {
 	return e
 }
 
+// LogAll controls if this logger will log all requests, or only requests that contain
+// logs written to the request Logger (default: true)
+func (e *ConsoleExporter) LogAll(v bool) *ConsoleExporter {
+	e.logAll = v
+
+	return e
+}
+
+// Repanic controls if a panic in the handler is propagated after it is logged. When false,
+// the panic is recovered and a 500 Internal Server Error is sent if the response has not
+// been started (default: false)
+func (e *ConsoleExporter) Repanic(v bool) *ConsoleExporter {
+	e.repanic = v
+
+	return e
+}
+
+// Options applies opts to the request logging of the exporter
+func (e *ConsoleExporter) Options(opts ...Option) *ConsoleExporter {
+	for _, opt := range opts {
+		opt(&e.config)
+	}
+
+	return e
+}
+
+// UnaryServerInterceptor returns a gRPC interceptor that injects a Logger into the context of unary calls,
+// and writes a request log for each call. The SeverityPolicy applies to the HTTP status equivalent to the
+// gRPC status code of the call.
+func (e *ConsoleExporter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return (&grpcHandler{sink: &consoleSink{noColor: e.noColor, policy: e.severityPolicy}, config: e.config}).unary
+}
+
+// StreamServerInterceptor returns a gRPC interceptor that injects a Logger into the context of streams,
+// and writes a request log for each stream.
+func (e *ConsoleExporter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return (&grpcHandler{sink: &consoleSink{noColor: e.noColor, policy: e.severityPolicy}, config: e.config}).stream
+}
+
+// StartOperation starts logging work done outside an HTTP request, such as a job or a message consumer.
+// Logs written with Ctx to the returned context are correlated by a log of the operation, written with
+// its duration and outcome when end is called. A non-nil err marks the operation as failed.
+func (e *ConsoleExporter) StartOperation(ctx context.Context, name string) (context.Context, func(err error)) {
+	return e.startOperation(ctx, &consoleSink{noColor: e.noColor, policy: e.severityPolicy}, name)
+}
+
 // Middleware returns a middleware that exports logs to Google Cloud Logging
 func (e *ConsoleExporter) Middleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
-		return &consoleHandler{
-			next:    next,
-			noColor: e.noColor,
+		return &requestHandler{
+			next:   next,
+			sink:   &consoleSink{noColor: e.noColor, policy: e.severityPolicy},
+			config: e.config,
 		}
 	}
 }
 
-type consoleHandler struct {
-	next    http.Handler
+// consoleSink logs all output to console
+type consoleSink struct {
 	noColor bool
+	policy  *SeverityPolicy
 }
 
-func (c *consoleHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	r = r.WithContext(newContext(r.Context(), newConsoleLogger(r, c.noColor)))
-	c.next.ServeHTTP(w, r)
-}
+// WriteParent writes a summary line for the request. A message set by WithParentMessage is written
+// after the method, path, latency, size and client IP.
+func (s *consoleSink) WriteParent(rec *Record) {
+	req := rec.Request
+	if req == nil {
+		s.writeOperation(rec)
 
-type consoleLogger struct {
-	r       *http.Request
-	noColor bool
-}
+		return
+	}
+	remoteIP := req.RemoteIP
+	if remoteIP == "" {
+		remoteIP = "-"
+	}
 
-// newConsoleLogger logs all output to console
-func newConsoleLogger(r *http.Request, noColor bool) *consoleLogger {
-	return &consoleLogger{r: r, noColor: noColor}
-}
+	// The default message only repeats the summary
+	var msg string
+	if m := fmt.Sprint(rec.Message); rec.Message != nil && m != "" && m != defaultParentMessage(req) {
+		msg = " " + m
+	}
 
-// Debug logs a debug message.
-func (l *consoleLogger) Debug(_ context.Context, v interface{}) {
-	l.console("DEBUG", gray, v)
+	log.Printf("%s: %s %s %s %dB %s%s%s",
+		s.colorPrint(statusLabel(req.Status), statusColor(s.policy.Severity(req.Status, req.Latency), req.Status)),
+		req.Request.Method, req.Request.URL.Path, req.Latency.Round(time.Microsecond), req.ResponseSize, remoteIP, msg, formatFields(rec.Fields),
+	)
 }
 
-// Debugf logs a debug message with format.
-func (l *consoleLogger) Debugf(_ context.Context, format string, v ...interface{}) {
-	l.consolef("DEBUG", gray, format, v...)
+// writeOperation writes a summary line for an operation started with StartOperation, or a gRPC call.
+// Like a request, it is labelled with its outcome or status rather than its severity, and the fields
+// already in the summary are left out.
+func (s *consoleSink) writeOperation(rec *Record) {
+	if group, fields, ok := splitGroup(rec.Fields, "grpc", "method", "code", "latency"); ok {
+		s.writeCall(rec, group, fields)
+
+		return
+	}
+
+	group, fields, ok := splitGroup(rec.Fields, "operation", "name", "outcome", "duration")
+	if !ok {
+		label, c := levelLabel(rec.Severity)
+		log.Printf("%s: %s%s", s.colorPrint(label, c), rec.Message, formatFields(rec.Fields))
+
+		return
+	}
+
+	label := "OK   "
+	if groupValue(group, "outcome") == "failure" {
+		label = "FAIL "
+	}
+
+	log.Printf("%s: %s %s%s",
+		s.colorPrint(label, statusColor(rec.Severity, http.StatusOK)), rec.Operation, consoleDuration(groupValue(group, "duration")), formatFields(fields),
+	)
 }
 
-// Info logs a info message.
-func (l *consoleLogger) Info(_ context.Context, v interface{}) {
-	l.console("INFO ", blue, v)
+// writeCall writes a summary line for a gRPC call, labelled with the HTTP status equivalent to its code.
+// A message set by WithParentMessage is written after the method, code and latency.
+func (s *consoleSink) writeCall(rec *Record, group, fields []Field) {
+	method := fmt.Sprint(groupValue(group, "method"))
+	code := fmt.Sprint(groupValue(group, "code"))
+	latency, _ := time.ParseDuration(fmt.Sprint(groupValue(group, "latency")))
+	status := grpcStatus(grpcCode(code))
+
+	// The default message only repeats the summary
+	var msg string
+	if m := fmt.Sprint(rec.Message); rec.Message != nil && m != "" && m != callMessage(method, code, latency) {
+		msg = " " + m
+	}
+
+	log.Printf("%s: %s %s %s%s%s",
+		s.colorPrint(statusLabel(status), statusColor(s.policy.Severity(status, latency), status)),
+		method, code, latency.Round(time.Microsecond), msg, formatFields(fields),
+	)
 }
 
-// Infof logs a info message with format.
-func (l *consoleLogger) Infof(_ context.Context, format string, v ...interface{}) {
-	l.consolef("INFO ", blue, format, v...)
+// WriteChild writes a log generated during the request
+func (s *consoleSink) WriteChild(rec *Record) {
+	label, c := levelLabel(rec.Severity)
+	scope := rec.Operation
+	if rec.Request != nil {
+		scope = rec.Request.Request.Method + " " + rec.Request.Request.URL.Path
+	}
+	msg := fmt.Sprintf("%s: %s %s%s%s%s", s.colorPrint(label, c), scope, consoleValue(rec.Message), formatFields(rec.Fields),
+		formatSource(rec.Source), errorTrace(rec.Message, rec.Fields))
+	if rec.Stack != "" {
+		msg += "\n" + rec.Stack
+	}
+	log.Print(msg)
 }
 
-// Warn logs a warning message.
-func (l *consoleLogger) Warn(_ context.Context, v interface{}) {
-	l.console("WARN ", yellow, v)
+func (s *consoleSink) colorPrint(str string, c color) string {
+	if s.noColor {
+		return str
+	}
+
+	return string([]byte{0x1b, '[', byte('0' + c/10), byte('0' + c%10), 'm'}) + str + "\x1b[0m"
 }
 
-// Warnf logs a warning message with format.
-func (l *consoleLogger) Warnf(_ context.Context, format string, v ...interface{}) {
-	l.consolef("WARN ", yellow, format, v...)
+// levelLabel returns the fixed width label and color used to print a severity
+func levelLabel(severity logging.Severity) (string, color) {
+	switch {
+	case severity < logging.Debug:
+		return "LOG  ", gray
+	case severity < logging.Info:
+		return "DEBUG", gray
+	case severity < logging.Notice:
+		return "INFO ", blue
+	case severity < logging.Warning:
+		return "NOTE ", cyan
+	case severity < logging.Error:
+		return "WARN ", yellow
+	case severity < logging.Critical:
+		return "ERROR", red
+	case severity < logging.Alert:
+		return "CRIT ", magenta
+	case severity < logging.Emergency:
+		return "ALERT", magenta
+	default:
+		return "EMERG", magenta
+	}
 }
 
-// Error logs an error message.
-func (l *consoleLogger) Error(_ context.Context, v interface{}) {
-	l.console("ERROR", red, v)
+// statusLabel returns the status code padded to the width of a level label
+func statusLabel(status int) string {
+	l := strconv.Itoa(status)
+	for len(l) < 5 {
+		l += " "
+	}
+
+	return l
 }
 
-// Errorf logs an error message with format.
-func (l *consoleLogger) Errorf(_ context.Context, format string, v ...interface{}) {
-	l.consolef("ERROR", red, format, v...)
+// statusColor returns the color used to print a response status, from the severity given by the SeverityPolicy
+func statusColor(severity logging.Severity, status int) color {
+	switch {
+	case severity >= logging.Error:
+		return red
+	case severity >= logging.Warning:
+		return yellow
+	case status >= 300:
+		return cyan
+	default:
+		return green
+	}
 }
 
-func (l *consoleLogger) console(level string, c color, v interface{}) {
-	log.Printf(l.colorPrint(level, c)+": %s %s", l.r.URL.Path, v)
+// splitGroup returns the fields of the group key, and fields with the keys in omit left out of the group.
+// The group is left out entirely if it has no other fields. It reports false if there is no group key.
+func splitGroup(fields []Field, key string, omit ...string) ([]Field, []Field, bool) {
+	for i, f := range fields {
+		group, ok := f.Value.([]Field)
+		if f.Key != key || !ok {
+			continue
+		}
+
+		rest := make([]Field, 0, len(fields))
+		rest = append(rest, fields[:i]...)
+		var kept []Field
+		for _, gf := range group {
+			if !slices.Contains(omit, gf.Key) {
+				kept = append(kept, gf)
+			}
+		}
+		if len(kept) != 0 {
+			rest = append(rest, Field{Key: key, Value: kept})
+		}
+
+		return group, append(rest, fields[i+1:]...), true
+	}
+
+	return nil, fields, false
 }
 
-func (l *consoleLogger) consolef(level string, c color, format string, v ...interface{}) {
-	log.Printf(l.colorPrint(level, c)+": "+l.r.Method+" "+l.r.URL.Path+" "+format, v...)
+// groupValue returns the value of key in group, or nil
+func groupValue(group []Field, key string) interface{} {
+	for _, f := range group {
+		if f.Key == key {
+			return f.Value
+		}
+	}
+
+	return nil
 }
 
-func (l *consoleLogger) colorPrint(s string, c color) string {
-	if l.noColor {
-		return s
+// consoleDuration rounds a duration recorded as a string to microseconds, as in the summary of a request
+func consoleDuration(v interface{}) string {
+	d, err := time.ParseDuration(fmt.Sprint(v))
+	if err != nil {
+		return fmt.Sprint(v)
 	}
 
-	return string([]byte{0x1b, '[', byte('0' + c/10), byte('0' + c%10), 'm'}) + s + "\x1b[0m"
+	return d.Round(time.Microsecond).String()
 }
@@ -4,13 +4,17 @@ import (
 	"bytes"
 	"context"
 	"log"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"cloud.google.com/go/logging"
 	"github.com/go-test/deep"
 )
 
@@ -23,7 +27,7 @@ func TestNewConsoleExporter(t *testing.T) {
 	}{
 		{
 			name: "Simple Constructor",
-			want: &ConsoleExporter{},
+			want: &ConsoleExporter{config: config{logAll: true}},
 		},
 	}
 	for _, tt := range tests {
@@ -91,11 +95,75 @@ func TestConsoleExporter_NoColor(t *testing.T) {
 	}
 }
 
+func TestConsoleExporter_LogAll(t *testing.T) {
+	t.Parallel()
+
+	type fields struct {
+		logAll bool
+	}
+	type args struct {
+		v bool
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+		want   *ConsoleExporter
+	}{
+		{
+			name: "logAll=true",
+			args: args{
+				v: true,
+			},
+			want: &ConsoleExporter{
+				config: config{logAll: true},
+			},
+		},
+		{
+			name: "logAll=false",
+			fields: fields{
+				logAll: true,
+			},
+			args: args{
+				v: false,
+			},
+			want: &ConsoleExporter{
+				config: config{logAll: false},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			e := &ConsoleExporter{
+				config: config{logAll: tt.fields.logAll},
+			}
+			if got := e.LogAll(tt.args.v); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ConsoleExporter.LogAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConsoleExporter_Repanic(t *testing.T) {
+	t.Parallel()
+
+	for _, v := range []bool{true, false} {
+		e := &ConsoleExporter{config: config{repanic: !v}}
+		want := &ConsoleExporter{config: config{repanic: v}}
+		if got := e.Repanic(v); !reflect.DeepEqual(got, want) {
+			t.Errorf("ConsoleExporter.Repanic() = %v, want %v", got, want)
+		}
+	}
+}
+
 func TestConsoleExporter_Middleware(t *testing.T) {
 	t.Parallel()
 
 	type fields struct {
 		noColor bool
+		logAll  bool
 	}
 	tests := []struct {
 		name   string
@@ -106,11 +174,13 @@ func TestConsoleExporter_Middleware(t *testing.T) {
 			name: "call Middleware",
 			fields: fields{
 				noColor: true,
+				logAll:  true,
 			},
 			want: func(next http.Handler) http.Handler {
-				return &consoleHandler{
-					next:    next,
-					noColor: true,
+				return &requestHandler{
+					next:   next,
+					sink:   &consoleSink{noColor: true},
+					config: config{logAll: true},
 				}
 			},
 		},
@@ -122,6 +192,7 @@ func TestConsoleExporter_Middleware(t *testing.T) {
 			next := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
 			e := &ConsoleExporter{
 				noColor: tt.fields.noColor,
+				config:  config{logAll: tt.fields.logAll},
 			}
 			got := e.Middleware()(next)
 			if diff := deep.Equal(got, tt.want(next)); diff != nil {
@@ -131,7 +202,7 @@ func TestConsoleExporter_Middleware(t *testing.T) {
 	}
 }
 
-func Test_consoleHandler_ServeHTTP(t *testing.T) {
+func TestConsoleExporter_ServeHTTP(t *testing.T) {
 	t.Parallel()
 
 	tests := []struct {
@@ -147,13 +218,14 @@ func Test_consoleHandler_ServeHTTP(t *testing.T) {
 			t.Parallel()
 
 			var handlerCalled bool
-			c := &consoleHandler{
-				next: http.HandlerFunc(
-					func(w http.ResponseWriter, r *http.Request) {
-						handlerCalled = true
-					},
-				),
-			}
+			c := NewConsoleExporter().Middleware()(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					if _, ok := Req(r).lg.(*requestLogger); !ok {
+						t.Errorf("Req() = %T, wanted: %T", Req(r).lg, &requestLogger{})
+					}
+					handlerCalled = true
+				},
+			))
 
 			w := httptest.NewRecorder()
 			r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
@@ -166,39 +238,7 @@ func Test_consoleHandler_ServeHTTP(t *testing.T) {
 	}
 }
 
-func TestNewConsoleLogger(t *testing.T) {
-	t.Parallel()
-
-	type args struct {
-		r       *http.Request
-		noColor bool
-	}
-	tests := []struct {
-		name string
-		args args
-		want ctxLogger
-	}{
-		{
-			name: "some request",
-			args: args{
-				r:       &http.Request{},
-				noColor: true,
-			},
-			want: &consoleLogger{r: &http.Request{}, noColor: true},
-		},
-	}
-	for _, tt := range tests {
-		tt := tt
-		t.Run(tt.name, func(t *testing.T) {
-			t.Parallel()
-			if got := newConsoleLogger(tt.args.r, tt.args.noColor); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("NewConsoleLogger() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
-func Test_consoleLogger(t *testing.T) {
+func Test_consoleSink_WriteChild(t *testing.T) {
 	type args struct {
 		v       []interface{}
 		v2      interface{}
@@ -218,77 +258,335 @@ func Test_consoleLogger(t *testing.T) {
 	}{
 		{
 			name: "Test with color", args: args{v: []interface{}{"Message"}, v2: "Message"},
-			wantDebug: "\x1b[37mDEBUG\x1b[0m: /path Message\n", wantDebugf: "\x1b[37mDEBUG\x1b[0m: GET /path Formatted Message\n",
-			wantInfo: "\x1b[34mINFO \x1b[0m: /path Message\n", wantInfof: "\x1b[34mINFO \x1b[0m: GET /path Formatted Message\n",
-			wantWarn: "\x1b[33mWARN \x1b[0m: /path Message\n", wantWarnf: "\x1b[33mWARN \x1b[0m: GET /path Formatted Message\n",
-			wantError: "\x1b[31mERROR\x1b[0m: /path Message\n", wantErrorf: "\x1b[31mERROR\x1b[0m: GET /path Formatted Message\n",
+			wantDebug: "\x1b[37mDEBUG\x1b[0m: GET /path Message\n", wantDebugf: "\x1b[37mDEBUG\x1b[0m: GET /path Formatted Message\n",
+			wantInfo: "\x1b[34mINFO \x1b[0m: GET /path Message\n", wantInfof: "\x1b[34mINFO \x1b[0m: GET /path Formatted Message\n",
+			wantWarn: "\x1b[33mWARN \x1b[0m: GET /path Message\n", wantWarnf: "\x1b[33mWARN \x1b[0m: GET /path Formatted Message\n",
+			wantError: "\x1b[31mERROR\x1b[0m: GET /path Message\n", wantErrorf: "\x1b[31mERROR\x1b[0m: GET /path Formatted Message\n",
 		},
 		{
 			name: "Test no color", args: args{v: []interface{}{"Message"}, v2: "Message", noColor: true},
-			wantDebug: "DEBUG: /path Message\n", wantDebugf: "DEBUG: GET /path Formatted Message\n",
-			wantInfo: "INFO : /path Message\n", wantInfof: "INFO : GET /path Formatted Message\n",
-			wantWarn: "WARN : /path Message\n", wantWarnf: "WARN : GET /path Formatted Message\n",
-			wantError: "ERROR: /path Message\n", wantErrorf: "ERROR: GET /path Formatted Message\n",
+			wantDebug: "DEBUG: GET /path Message\n", wantDebugf: "DEBUG: GET /path Formatted Message\n",
+			wantInfo: "INFO : GET /path Message\n", wantInfof: "INFO : GET /path Formatted Message\n",
+			wantWarn: "WARN : GET /path Message\n", wantWarnf: "WARN : GET /path Formatted Message\n",
+			wantError: "ERROR: GET /path Message\n", wantErrorf: "ERROR: GET /path Formatted Message\n",
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			var buf bytes.Buffer
-			ctx := context.Background()
 			log.SetOutput(&buf)
 			t.Cleanup(func() { log.SetOutput(os.Stderr) })
 
 			u, _ := url.Parse("http://some.domain.com/path")
-			l := &consoleLogger{r: &http.Request{Method: http.MethodGet, URL: u}, noColor: tt.args.noColor}
+			r := &http.Request{Method: http.MethodGet, URL: u}
+			l := Ctx(newContext(context.Background(), newRequestLogger(&consoleSink{noColor: tt.args.noColor}, r, "")))
 			format := "Formatted %s"
 
-			l.Debug(ctx, tt.args.v2)
+			l.Debug(tt.args.v2)
 			if s := buf.String(); s[20:] != tt.wantDebug {
-				t.Errorf("stdErrLogger.Debug() value = %v, wantValue %v", s[20:], tt.wantDebug)
+				t.Errorf("consoleSink Debug() value = %v, wantValue %v", s[20:], tt.wantDebug)
 			}
 			buf.Reset()
 
-			l.Debugf(ctx, format, tt.args.v...)
+			l.Debugf(format, tt.args.v...)
 			if s := buf.String(); s[20:] != tt.wantDebugf {
-				t.Errorf("stdErrLogger.Debug() value = %v, wantValue %v", s[20:], tt.wantDebugf)
+				t.Errorf("consoleSink Debugf() value = %v, wantValue %v", s[20:], tt.wantDebugf)
 			}
 			buf.Reset()
 
-			l.Info(ctx, tt.args.v2)
+			l.Info(tt.args.v2)
 			if s := buf.String(); s[20:] != tt.wantInfo {
-				t.Errorf("stdErrLogger.Info() value = %v, wantValue %v", s[20:], tt.wantInfo)
+				t.Errorf("consoleSink Info() value = %v, wantValue %v", s[20:], tt.wantInfo)
 			}
 			buf.Reset()
 
-			l.Infof(ctx, format, tt.args.v...)
+			l.Infof(format, tt.args.v...)
 			if s := buf.String(); s[20:] != tt.wantInfof {
-				t.Errorf("stdErrLogger.Info() value = %v, wantValue %v", s[20:], tt.wantInfof)
+				t.Errorf("consoleSink Infof() value = %v, wantValue %v", s[20:], tt.wantInfof)
 			}
 			buf.Reset()
 
-			l.Warn(ctx, tt.args.v2)
+			l.Warn(tt.args.v2)
 			if s := buf.String(); s[20:] != tt.wantWarn {
-				t.Errorf("stdErrLogger.Warn() value = %v, wantValue %v", s[20:], tt.wantWarn)
+				t.Errorf("consoleSink Warn() value = %v, wantValue %v", s[20:], tt.wantWarn)
 			}
 			buf.Reset()
 
-			l.Warnf(ctx, format, tt.args.v...)
+			l.Warnf(format, tt.args.v...)
 			if s := buf.String(); s[20:] != tt.wantWarnf {
-				t.Errorf("stdErrLogger.Warn() value = %v, wantValue %v", s[20:], tt.wantWarnf)
+				t.Errorf("consoleSink Warnf() value = %v, wantValue %v", s[20:], tt.wantWarnf)
 			}
 			buf.Reset()
 
-			l.Error(ctx, tt.args.v2)
+			l.Error(tt.args.v2)
 			if s := buf.String(); s[20:] != tt.wantError {
-				t.Errorf("stdErrLogger.Error() value = %v, wantValue %v", s[20:], tt.wantError)
+				t.Errorf("consoleSink Error() value = %v, wantValue %v", s[20:], tt.wantError)
 			}
 			buf.Reset()
 
-			l.Errorf(ctx, format, tt.args.v...)
+			l.Errorf(format, tt.args.v...)
 			if s := buf.String(); s[20:] != tt.wantErrorf {
-				t.Errorf("stdErrLogger.Error() value = %v, wantValue %v", s[20:], tt.wantErrorf)
+				t.Errorf("consoleSink Errorf() value = %v, wantValue %v", s[20:], tt.wantErrorf)
 			}
 			buf.Reset()
 		})
 	}
 }
+
+func Test_consoleSink_WriteChild_stack(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	r := httptest.NewRequest(http.MethodGet, "/path", http.NoBody)
+	(&consoleSink{noColor: true}).WriteChild(&Record{
+		Severity: logging.Error,
+		Message:  "panic: boom",
+		Request:  &HTTPRequest{Request: r},
+		Stack:    "goroutine 1 [running]:\nmain.main()",
+	})
+
+	want := "ERROR: GET /path panic: boom\ngoroutine 1 [running]:\nmain.main()\n"
+	if s := buf.String(); s[20:] != want {
+		t.Errorf("consoleSink.WriteChild() value = %v, wantValue %v", s[20:], want)
+	}
+}
+
+func Test_consoleSink_WriteChild_source(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	r := httptest.NewRequest(http.MethodGet, "/path", http.NoBody)
+	(&consoleSink{noColor: true}).WriteChild(&Record{
+		Severity: logging.Info,
+		Message:  "Message",
+		Request:  &HTTPRequest{Request: r},
+		Fields:   []Field{{Key: "order_id", Value: 123}},
+		Source:   &slog.Source{Function: "main.main", File: "/src/app/main.go", Line: 42},
+	})
+
+	want := "INFO : GET /path Message order_id=123 main.go:42\n"
+	if s := buf.String(); s[20:] != want {
+		t.Errorf("consoleSink.WriteChild() value = %v, wantValue %v", s[20:], want)
+	}
+}
+
+func Test_consoleSink_fields(t *testing.T) {
+	fields := []Field{{Key: "order_id", Value: 123}, {Key: "tenant", Value: "acme corp"}}
+	tests := []struct {
+		name     string
+		noColor  bool
+		severity logging.Severity
+		want     string
+	}{
+		{
+			name:     "Test with color",
+			severity: logging.Info,
+			want:     "\x1b[34mINFO \x1b[0m: GET /path Message order_id=123 tenant=\"acme corp\"\n",
+		},
+		{
+			name:     "Test no color",
+			noColor:  true,
+			severity: logging.Error,
+			want:     "ERROR: GET /path Message order_id=123 tenant=\"acme corp\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log.SetOutput(&buf)
+			t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+			u, _ := url.Parse("http://some.domain.com/path")
+			s := &consoleSink{noColor: tt.noColor}
+			s.WriteChild(&Record{
+				Severity: tt.severity,
+				Message:  "Message",
+				Fields:   fields,
+				Request:  &HTTPRequest{Request: &http.Request{Method: http.MethodGet, URL: u}},
+			})
+			if got := buf.String(); got[20:] != tt.want {
+				t.Errorf("consoleSink.WriteChild() value = %v, wantValue %v", got[20:], tt.want)
+			}
+		})
+	}
+}
+
+func Test_levelLabel(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		severity  logging.Severity
+		wantLabel string
+		wantColor color
+	}{
+		{name: "Default", severity: logging.Default, wantLabel: "LOG  ", wantColor: gray},
+		{name: "Debug", severity: logging.Debug, wantLabel: "DEBUG", wantColor: gray},
+		{name: "Info", severity: logging.Info, wantLabel: "INFO ", wantColor: blue},
+		{name: "Notice", severity: logging.Notice, wantLabel: "NOTE ", wantColor: cyan},
+		{name: "Warning", severity: logging.Warning, wantLabel: "WARN ", wantColor: yellow},
+		{name: "Error", severity: logging.Error, wantLabel: "ERROR", wantColor: red},
+		{name: "Critical", severity: logging.Critical, wantLabel: "CRIT ", wantColor: magenta},
+		{name: "Alert", severity: logging.Alert, wantLabel: "ALERT", wantColor: magenta},
+		{name: "Emergency", severity: logging.Emergency, wantLabel: "EMERG", wantColor: magenta},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			label, c := levelLabel(tt.severity)
+			if label != tt.wantLabel {
+				t.Errorf("levelLabel() label = %v, want %v", label, tt.wantLabel)
+			}
+			if c != tt.wantColor {
+				t.Errorf("levelLabel() color = %v, want %v", c, tt.wantColor)
+			}
+		})
+	}
+}
+
+func Test_consoleSink_WriteParent(t *testing.T) {
+	u, _ := url.Parse("http://some.domain.com/path")
+	tests := []struct {
+		name    string
+		noColor bool
+		message string
+		req     *HTTPRequest
+		want    string
+	}{
+		{
+			name:    "Test with color",
+			message: "GET /path 200 12ms",
+			req: &HTTPRequest{
+				Request:      &http.Request{Method: http.MethodGet, URL: u},
+				Status:       http.StatusOK,
+				ResponseSize: 512,
+				Latency:      12345678 * time.Nanosecond,
+				RemoteIP:     "192.168.1.1",
+			},
+			want: "\x1b[32m200  \x1b[0m: GET /path 12.346ms 512B 192.168.1.1\n",
+		},
+		{
+			name:    "Test no color",
+			noColor: true,
+			message: "upstream failed",
+			req: &HTTPRequest{
+				Request: &http.Request{Method: http.MethodPost, URL: u},
+				Status:  http.StatusBadGateway,
+				Latency: time.Second,
+			},
+			want: "502  : POST /path 1s 0B - upstream failed\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log.SetOutput(&buf)
+			t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+			s := &consoleSink{noColor: tt.noColor}
+			s.WriteParent(&Record{Message: tt.message, Request: tt.req})
+			if got := buf.String(); got[20:] != tt.want {
+				t.Errorf("consoleSink.WriteParent() value = %q, wantValue %q", got[20:], tt.want)
+			}
+		})
+	}
+}
+
+func Test_consoleSink_WriteParent_grpc(t *testing.T) {
+	grpcFields := func(code string) []Field {
+		return []Field{{Key: "grpc", Value: []Field{
+			{Key: "method", Value: "/pkg.Svc/Method"},
+			{Key: "code", Value: code},
+			{Key: "peer", Value: "10.0.0.1"},
+			{Key: "latency", Value: "1.5ms"},
+		}}}
+	}
+
+	tests := []struct {
+		name    string
+		noColor bool
+		rec     *Record
+		want    string
+	}{
+		{
+			name: "OK without logs",
+			rec:  &Record{Severity: logging.Default, Message: "/pkg.Svc/Method OK 2ms", Fields: grpcFields("OK"), Operation: "/pkg.Svc/Method"},
+			want: "\x1b[32m200  \x1b[0m: /pkg.Svc/Method OK 1.5ms grpc.peer=10.0.0.1\n",
+		},
+		{
+			name:    "Internal",
+			noColor: true,
+			rec:     &Record{Severity: logging.Error, Message: "/pkg.Svc/Method Internal 2ms", Fields: grpcFields("Internal"), Operation: "/pkg.Svc/Method"},
+			want:    "500  : /pkg.Svc/Method Internal 1.5ms grpc.peer=10.0.0.1\n",
+		},
+		{
+			name:    "custom message",
+			noColor: true,
+			rec:     &Record{Severity: logging.Info, Message: "order not found", Fields: grpcFields("NotFound"), Operation: "/pkg.Svc/Method"},
+			want:    "404  : /pkg.Svc/Method NotFound 1.5ms order not found grpc.peer=10.0.0.1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log.SetOutput(&buf)
+			t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+			(&consoleSink{noColor: tt.noColor}).WriteParent(tt.rec)
+			if got := buf.String(); got[20:] != tt.want {
+				t.Errorf("consoleSink.WriteParent() value = %q, wantValue %q", got[20:], tt.want)
+			}
+		})
+	}
+}
+
+func TestConsoleExporter_ParentMessage(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	h := NewConsoleExporter().NoColor(true).Options(WithParentMessage(func(req *HTTPRequest) string {
+		return "served " + req.Request.URL.Path
+	})).Middleware()(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/path", http.NoBody))
+
+	// The latency varies between runs
+	s := buf.String()[20:]
+	if !strings.HasPrefix(s, "200  : GET /path ") || !strings.HasSuffix(s, " 0B 192.0.2.1 served /path\n") {
+		t.Errorf("consoleSink.WriteParent() value = %q, want the summary followed by %q", s, "served /path")
+	}
+}
+
+func Test_statusColor(t *testing.T) {
+	t.Parallel()
+
+	policy := &SeverityPolicy{ClientError: logging.Warning, ServerError: logging.Error, Status: map[int]logging.Severity{http.StatusNotFound: logging.Info}}
+
+	tests := []struct {
+		name   string
+		policy *SeverityPolicy
+		status int
+		want   color
+	}{
+		{name: "1xx", status: http.StatusSwitchingProtocols, want: green},
+		{name: "2xx", status: http.StatusCreated, want: green},
+		{name: "3xx", status: http.StatusFound, want: cyan},
+		{name: "4xx", status: http.StatusNotFound, want: red},
+		{name: "5xx", status: http.StatusServiceUnavailable, want: red},
+		{name: "4xx policy", policy: policy, status: http.StatusUnauthorized, want: yellow},
+		{name: "4xx policy override", policy: policy, status: http.StatusNotFound, want: cyan},
+		{name: "5xx policy", policy: policy, status: http.StatusBadGateway, want: red},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := statusColor(tt.policy.Severity(tt.status, 0), tt.status); got != tt.want {
+				t.Errorf("statusColor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
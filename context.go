@@ -3,6 +3,8 @@ package logger
 import (
 	"context"
 	"net/http"
+
+	"cloud.google.com/go/logging"
 )
 
 type key int
@@ -41,20 +43,11 @@ func newContext(ctx context.Context, l ctxLogger) context.Context {
 
 // ctxLogger defines the logging interface with context
 type ctxLogger interface {
-	// Debug logs a debug message.
-	Debug(ctx context.Context, v interface{})
-	// Debugf logs a debug message with format.
-	Debugf(ctx context.Context, format string, v ...interface{})
-	// Info logs a info message.
-	Info(ctx context.Context, v interface{})
-	// Infof logs a info message with format.
-	Infof(ctx context.Context, format string, v ...interface{})
-	// Warn logs a warning message.
-	Warn(ctx context.Context, v interface{})
-	// Warnf logs a warning message with format.
-	Warnf(ctx context.Context, format string, v ...interface{})
-	// Error logs an error message.
-	Error(ctx context.Context, v interface{})
-	// Errorf logs an error message with format.
-	Errorf(ctx context.Context, format string, v ...interface{})
+	// Log writes a log with severity and fields. pc is the program counter of the
+	// caller, used for the source location of the log, or 0 if it is unknown.
+	Log(ctx context.Context, severity logging.Severity, v interface{}, fields []Field, pc uintptr)
+	// SetRequestField adds a field to the request log.
+	SetRequestField(key string, value interface{})
+	// SetRoute sets the route template matched by the request.
+	SetRoute(route string)
 }
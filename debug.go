@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DebugHeader is the request header holding a debug token. When an Exporter is configured with
+// WithDebugSecret, a request with a valid token has all of its logs written, regardless of the level.
+const DebugHeader = "X-Debug-Log"
+
+// NewDebugToken returns a token for DebugHeader, signed with secret, that is valid until expires
+func NewDebugToken(secret []byte, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+
+	return exp + "." + debugSignature(secret, exp)
+}
+
+// verifyDebugToken reports if token was signed with secret and has not expired
+func verifyDebugToken(secret []byte, token string, now time.Time) bool {
+	exp, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || !now.Before(time.Unix(unix, 0)) {
+		return false
+	}
+
+	return hmac.Equal([]byte(sig), []byte(debugSignature(secret, exp)))
+}
+
+// debugSignature returns the base64 encoded HMAC-SHA256 of exp
+func debugSignature(secret []byte, exp string) string {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(exp))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// debugEnabled reports if the request headers have a valid debug token
+func (c config) debugEnabled(header http.Header) bool {
+	if len(c.debugSecret) == 0 {
+		return false
+	}
+
+	token := header.Get(DebugHeader)
+
+	return token != "" && verifyDebugToken(c.debugSecret, token, time.Now())
+}
@@ -0,0 +1,136 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging"
+	"github.com/go-test/deep"
+)
+
+func Test_verifyDebugToken(t *testing.T) {
+	t.Parallel()
+
+	secret := []byte("secret")
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name  string
+		token string
+		want  bool
+	}{
+		{
+			name:  "valid",
+			token: NewDebugToken(secret, now.Add(time.Hour)),
+			want:  true,
+		},
+		{
+			name:  "expired",
+			token: NewDebugToken(secret, now),
+		},
+		{
+			name:  "wrong secret",
+			token: NewDebugToken([]byte("other"), now.Add(time.Hour)),
+		},
+		{
+			name:  "tampered expiry",
+			token: "1800000000" + NewDebugToken(secret, now.Add(time.Hour))[10:],
+		},
+		{
+			name:  "bad expiry",
+			token: "soon.abc",
+		},
+		{
+			name:  "no signature",
+			token: "1800000000",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := verifyDebugToken(secret, tt.token, now); got != tt.want {
+				t.Errorf("verifyDebugToken() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSinkExporter_DebugSecret(t *testing.T) {
+	t.Parallel()
+
+	secret := []byte("secret")
+
+	tests := []struct {
+		name         string
+		secret       []byte
+		token        string
+		buffer       bool
+		wantChildren int
+		wantFields   []Field
+	}{
+		{
+			name:         "valid token",
+			secret:       secret,
+			token:        NewDebugToken(secret, time.Now().Add(time.Minute)),
+			wantChildren: 2,
+			wantFields:   []Field{{Key: "debug", Value: true}},
+		},
+		{
+			name:         "invalid token",
+			secret:       secret,
+			token:        NewDebugToken([]byte("other"), time.Now().Add(time.Minute)),
+			wantChildren: 1,
+		},
+		{
+			name:         "valid token with buffer",
+			secret:       secret,
+			token:        NewDebugToken(secret, time.Now().Add(time.Minute)),
+			buffer:       true,
+			wantChildren: 2,
+			wantFields:   []Field{{Key: "debug", Value: true}},
+		},
+		{
+			name:         "invalid token with buffer",
+			secret:       secret,
+			token:        NewDebugToken([]byte("other"), time.Now().Add(time.Minute)),
+			buffer:       true,
+			wantChildren: 0,
+			wantFields:   []Field{{Key: "dropped_logs", Value: []Field{{Key: "Info", Value: 1}}}},
+		},
+		{
+			name:         "not configured",
+			token:        NewDebugToken(nil, time.Now().Add(time.Minute)),
+			wantChildren: 1,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &captureSink{}
+			h := NewSinkExporter(s).Options(WithLevel(NewLevelVar(logging.Info)), WithDebugSecret(tt.secret), WithBuffer(tt.buffer)).Middleware()(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					Req(r).Debug("debug log")
+					Req(r).Info("info log")
+				},
+			))
+			r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			r.Header.Set(DebugHeader, tt.token)
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if len(s.children) != tt.wantChildren {
+				t.Errorf("children = %d, want %d", len(s.children), tt.wantChildren)
+			}
+			if len(s.parents) != 1 {
+				t.Fatalf("parents = %d, want 1", len(s.parents))
+			}
+			if diff := deep.Equal(s.parents[0].Fields, tt.wantFields); diff != nil {
+				t.Errorf("parent Fields = %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package logger
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/errors/v5"
+)
+
+// errorChain returns the go-playground errors Chain in the tree of err, if any
+func errorChain(err error) (errors.Chain, bool) {
+	var c errors.Chain
+	if !errors.As(err, &c) || len(c) == 0 {
+		return nil, false
+	}
+
+	return c, true
+}
+
+// chainMessage returns the message of c, with the prefix of each wrap from the outermost
+// to the root error, and without the source location of each link
+func chainMessage(c errors.Chain) string {
+	parts := make([]string, 0, len(c)+1)
+	for i := len(c) - 1; i >= 0; i-- {
+		parts = append(parts, linkMessage(c, i))
+	}
+
+	return strings.Join(parts, ": ")
+}
+
+// linkMessage returns the message added by link i of c. The root link includes the wrapped error.
+func linkMessage(c errors.Chain, i int) string {
+	l := c[i]
+	if i != 0 {
+		return l.Prefix
+	}
+	if l.Prefix == "" {
+		return l.Err.Error()
+	}
+
+	return l.Prefix + ": " + l.Err.Error()
+}
+
+// chainPayload returns the message of c, and the message, source, tags and types of each link
+// from the outermost to the root, for a JSON payload
+func chainPayload(c errors.Chain) map[string]interface{} {
+	links := make([]map[string]interface{}, 0, len(c))
+	for i := len(c) - 1; i >= 0; i-- {
+		l := c[i]
+		link := map[string]interface{}{
+			"error": linkMessage(c, i),
+			"source": map[string]interface{}{
+				"file":     l.Source.Frame.File,
+				"line":     l.Source.Line(),
+				"function": l.Source.Frame.Function,
+			},
+		}
+		if len(l.Tags) != 0 {
+			link["tags"] = payloadValue(tagFields(l.Tags))
+		}
+		if len(l.Types) != 0 {
+			link["types"] = l.Types
+		}
+		links = append(links, link)
+	}
+
+	return map[string]interface{}{
+		"message": chainMessage(c),
+		"chain":   links,
+	}
+}
+
+// errorTrace returns an indented line for each link of the errors Chains in v and fields, for the console
+func errorTrace(v interface{}, fields []Field) string {
+	var b strings.Builder
+	writeTrace(&b, v)
+	for _, f := range fields {
+		writeTrace(&b, f.Value)
+	}
+
+	return b.String()
+}
+
+func writeTrace(b *strings.Builder, v interface{}) {
+	err, ok := v.(error)
+	if !ok {
+		return
+	}
+	c, ok := errorChain(err)
+	if !ok {
+		return
+	}
+
+	for i := len(c) - 1; i >= 0; i-- {
+		l := c[i]
+		b.WriteString("\n    ")
+		b.WriteString(filepath.Base(l.Source.Frame.File))
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(l.Source.Line()))
+		b.WriteByte(' ')
+		b.WriteString(l.Source.Frame.Function)
+		b.WriteString(": ")
+		b.WriteString(linkMessage(c, i))
+		b.WriteString(formatFields(tagFields(l.Tags)))
+		if len(l.Types) != 0 {
+			b.WriteString(" types=")
+			b.WriteString(strings.Join(l.Types, ","))
+		}
+	}
+}
+
+// tagFields converts errors Tags to Fields
+func tagFields(tags []errors.Tag) []Field {
+	fields := make([]Field, 0, len(tags))
+	for _, t := range tags {
+		fields = append(fields, Field{Key: t.Key, Value: t.Value})
+	}
+
+	return fields
+}
+
+// consoleValue returns v for printing on the console. An error with an errors Chain is replaced by
+// its message, as the source of each link is printed by errorTrace.
+func consoleValue(v interface{}) interface{} {
+	if err, ok := v.(error); ok {
+		if c, ok := errorChain(err); ok {
+			return chainMessage(c)
+		}
+	}
+
+	return v
+}
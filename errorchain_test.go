@@ -0,0 +1,135 @@
+package logger
+
+import (
+	stderrors "errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/errors/v5"
+	"github.com/go-test/deep"
+)
+
+func Test_chainMessage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "New",
+			err:  errors.New("boom"),
+			want: "boom",
+		},
+		{
+			name: "Wrap",
+			err:  errors.Wrap(errors.Wrap(io.EOF, "read body"), "load order"),
+			want: "load order: read body: EOF",
+		},
+		{
+			name: "wrapped by fmt",
+			err:  fmt.Errorf("handler: %w", errors.Wrap(io.EOF, "read body")),
+			want: "read body: EOF",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c, ok := errorChain(tt.err)
+			if !ok {
+				t.Fatal("errorChain() ok = false")
+			}
+			if got := chainMessage(c); got != tt.want {
+				t.Errorf("chainMessage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_errorChain_std(t *testing.T) {
+	t.Parallel()
+
+	if _, ok := errorChain(stderrors.New("boom")); ok {
+		t.Error("errorChain() ok = true, want false")
+	}
+}
+
+func Test_chainPayload(t *testing.T) {
+	t.Parallel()
+
+	err := errors.Wrap(io.EOF, "read body").AddTypes("Transient")
+	err = err.Wrap("load order").AddTag("order_id", 123)
+
+	got := chainPayload(err)
+	chain, _ := got["chain"].([]map[string]interface{})
+	if len(chain) != 2 {
+		t.Fatalf("chainPayload() chain = %v, want 2 links", got["chain"])
+	}
+	for _, link := range chain {
+		source, _ := link["source"].(map[string]interface{})
+		if file, _ := source["file"].(string); !strings.HasSuffix(file, "errorchain_test.go") {
+			t.Errorf("chainPayload() source = %v, want this file", source)
+		}
+		delete(link, "source")
+	}
+
+	want := map[string]interface{}{
+		"message": "load order: read body: EOF",
+		"chain": []map[string]interface{}{
+			{"error": "load order", "tags": map[string]interface{}{"order_id": 123}},
+			{"error": "read body: EOF", "types": []string{"Transient"}},
+		},
+	}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("chainPayload() = %v", diff)
+	}
+}
+
+func Test_errorTrace(t *testing.T) {
+	t.Parallel()
+
+	err := errors.Wrap(io.EOF, "read body").AddTypes("Transient")
+	err = err.Wrap("load order").AddTag("order_id", 123)
+
+	tests := []struct {
+		name   string
+		v      interface{}
+		fields []Field
+		want   string
+	}{
+		{
+			name: "message",
+			v:    err,
+			want: `^\n    errorchain_test\.go:\d+ github\.com/jtwatson/logger\.Test_errorTrace: load order order_id=123` +
+				`\n    errorchain_test\.go:\d+ github\.com/jtwatson/logger\.Test_errorTrace: read body: EOF types=Transient$`,
+		},
+		{
+			name:   "field",
+			v:      "Message",
+			fields: []Field{{Key: "error", Value: errors.New("boom")}},
+			want:   `^\n    errorchain_test\.go:\d+ github\.com/jtwatson/logger\.Test_errorTrace: boom$`,
+		},
+		{
+			name:   "std error",
+			v:      io.EOF,
+			fields: []Field{{Key: "error", Value: io.EOF}},
+			want:   `^$`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := errorTrace(tt.v, tt.fields); !regexp.MustCompile(tt.want).MatchString(got) {
+				t.Errorf("errorTrace() = %q, want match %q", got, tt.want)
+			}
+		})
+	}
+}
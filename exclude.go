@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Matcher reports if a request matches. A gRPC call is matched as a POST request to its full method,
+// such as "/grpc.health.v1.Health/Check", with the metadata as headers.
+type Matcher func(r *http.Request) bool
+
+// Exclusion skips the request log for requests matching Match, such as health checks and metrics
+// scrapes. A Logger is still injected into the context of excluded requests.
+type Exclusion struct {
+	// Match reports if a request is excluded
+	Match Matcher
+	// SuppressChildren also drops the logs written during excluded requests
+	SuppressChildren bool
+	// KeepFailures logs excluded requests that fail with a 5xx status or a panic as usual
+	KeepFailures bool
+}
+
+// ExactPath returns a Matcher for requests to one of the paths. A path may be prefixed
+// with a method and a space, such as "GET /healthz", to only match that method.
+func ExactPath(paths ...string) Matcher {
+	return matchPatterns(paths, func(p, pattern string) bool { return p == pattern })
+}
+
+// PathPrefix returns a Matcher for requests to a path starting with one of the prefixes. A prefix
+// may be prefixed with a method and a space, such as "GET /debug/", to only match that method.
+func PathPrefix(prefixes ...string) Matcher {
+	return matchPatterns(prefixes, strings.HasPrefix)
+}
+
+// PathGlob returns a Matcher for requests to a path matching one of the patterns, using the syntax
+// of path.Match. A pattern may be prefixed with a method and a space, such as "GET /*/healthz", to
+// only match that method.
+func PathGlob(patterns ...string) Matcher {
+	return matchPatterns(patterns, func(p, pattern string) bool {
+		ok, err := path.Match(pattern, p)
+
+		return err == nil && ok
+	})
+}
+
+// matchPatterns returns a Matcher for requests where match reports true for the path and
+// one of the patterns, and the method of the pattern (if any) is the request method
+func matchPatterns(patterns []string, match func(path, pattern string) bool) Matcher {
+	return func(r *http.Request) bool {
+		for _, pattern := range patterns {
+			if method, p, ok := strings.Cut(pattern, " "); ok {
+				if method != r.Method {
+					continue
+				}
+				pattern = p
+			}
+
+			if match(r.URL.Path, pattern) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// exclusion returns the first Exclusion matching the request, or nil
+func (c config) exclusion(r *http.Request) *Exclusion {
+	for i := range c.exclusions {
+		if c.exclusions[i].Match(r) {
+			return &c.exclusions[i]
+		}
+	}
+
+	return nil
+}
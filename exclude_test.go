@@ -0,0 +1,128 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMatchers(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		matcher Matcher
+		method  string
+		path    string
+		want    bool
+	}{
+		{name: "exact", matcher: ExactPath("/healthz", "/metrics"), method: http.MethodGet, path: "/metrics", want: true},
+		{name: "exact no match", matcher: ExactPath("/healthz"), method: http.MethodGet, path: "/healthz/db"},
+		{name: "exact method", matcher: ExactPath("GET /healthz"), method: http.MethodGet, path: "/healthz", want: true},
+		{name: "exact wrong method", matcher: ExactPath("GET /healthz"), method: http.MethodPost, path: "/healthz"},
+		{name: "prefix", matcher: PathPrefix("/debug/"), method: http.MethodGet, path: "/debug/pprof/heap", want: true},
+		{name: "prefix no match", matcher: PathPrefix("/debug/"), method: http.MethodGet, path: "/debugger"},
+		{name: "prefix wrong method", matcher: PathPrefix("HEAD /debug/"), method: http.MethodGet, path: "/debug/vars"},
+		{name: "glob", matcher: PathGlob("/*/healthz"), method: http.MethodGet, path: "/orders/healthz", want: true},
+		{name: "glob no match", matcher: PathGlob("/*/healthz"), method: http.MethodGet, path: "/a/b/healthz"},
+		{name: "glob bad pattern", matcher: PathGlob("/[/healthz"), method: http.MethodGet, path: "/[/healthz"},
+		{name: "glob method", matcher: PathGlob("GET /*/healthz"), method: http.MethodGet, path: "/orders/healthz", want: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.matcher(httptest.NewRequest(tt.method, tt.path, http.NoBody)); got != tt.want {
+				t.Errorf("Matcher() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSinkExporter_Exclude(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		exclusion    Exclusion
+		path         string
+		status       int
+		wantChildren int
+		wantParent   bool
+	}{
+		{
+			name:         "not excluded",
+			exclusion:    Exclusion{Match: ExactPath("/healthz")},
+			path:         "/orders",
+			status:       http.StatusOK,
+			wantChildren: 1,
+			wantParent:   true,
+		},
+		{
+			name:         "excluded",
+			exclusion:    Exclusion{Match: ExactPath("/healthz")},
+			path:         "/healthz",
+			status:       http.StatusOK,
+			wantChildren: 1,
+		},
+		{
+			name:      "excluded suppress children",
+			exclusion: Exclusion{Match: ExactPath("/healthz"), SuppressChildren: true},
+			path:      "/healthz",
+			status:    http.StatusOK,
+		},
+		{
+			name:      "excluded failure",
+			exclusion: Exclusion{Match: ExactPath("/healthz"), SuppressChildren: true},
+			path:      "/healthz",
+			status:    http.StatusServiceUnavailable,
+		},
+		{
+			name:         "excluded keep failures",
+			exclusion:    Exclusion{Match: ExactPath("/healthz"), SuppressChildren: true, KeepFailures: true},
+			path:         "/healthz",
+			status:       http.StatusServiceUnavailable,
+			wantChildren: 1,
+			wantParent:   true,
+		},
+		{
+			name:      "excluded keep failures success",
+			exclusion: Exclusion{Match: ExactPath("/healthz"), SuppressChildren: true, KeepFailures: true},
+			path:      "/healthz",
+			status:    http.StatusOK,
+		},
+		{
+			name: "predicate",
+			exclusion: Exclusion{Match: func(r *http.Request) bool {
+				return r.Header.Get("User-Agent") == "kube-probe/1.27"
+			}},
+			path:         "/",
+			status:       http.StatusOK,
+			wantChildren: 1,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &captureSink{}
+			h := NewSinkExporter(s).Options(WithExclude(tt.exclusion)).Middleware()(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					Req(r).Info("some log")
+					w.WriteHeader(tt.status)
+				},
+			))
+			r := httptest.NewRequest(http.MethodGet, tt.path, http.NoBody)
+			r.Header.Set("User-Agent", "kube-probe/1.27")
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if len(s.children) != tt.wantChildren {
+				t.Errorf("children = %d, want %d", len(s.children), tt.wantChildren)
+			}
+			if got := len(s.parents) == 1; got != tt.wantParent {
+				t.Errorf("parent written = %v, want %v", got, tt.wantParent)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const badKey = "!BADKEY"
+
+// Field is a key/value pair attached to a log entry.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// appendFields converts alternating key/value arguments into Fields and appends them to a copy
+// of fields. A Field may also be passed directly in place of a key/value pair. A value without
+// a string key is stored under the key "!BADKEY".
+func appendFields(fields []Field, kv []interface{}) []Field {
+	if len(kv) == 0 {
+		return fields
+	}
+
+	f := make([]Field, len(fields), len(fields)+len(kv))
+	copy(f, fields)
+
+	for len(kv) > 0 {
+		switch k := kv[0].(type) {
+		case Field:
+			f = append(f, k)
+			kv = kv[1:]
+		case string:
+			if len(kv) == 1 {
+				f = append(f, Field{Key: badKey, Value: k})
+				kv = kv[1:]
+
+				continue
+			}
+			f = append(f, Field{Key: k, Value: kv[1]})
+			kv = kv[2:]
+		default:
+			f = append(f, Field{Key: badKey, Value: k})
+			kv = kv[1:]
+		}
+	}
+
+	return f
+}
+
+// payloadValue returns a representation of v suitable for a JSON payload.
+func payloadValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case error:
+		if c, ok := errorChain(t); ok {
+			return chainPayload(c)
+		}
+
+		return t.Error()
+	case []Field:
+		m := make(map[string]interface{}, len(t))
+		for _, f := range t {
+			m[f.Key] = payloadValue(f.Value)
+		}
+
+		return m
+	}
+
+	return v
+}
+
+// formatFields renders fields as space prefixed key=value pairs. Grouped fields
+// are flattened using dot separated keys.
+func formatFields(fields []Field) string {
+	var b strings.Builder
+	writeFields(&b, "", fields)
+
+	return b.String()
+}
+
+func writeFields(b *strings.Builder, prefix string, fields []Field) {
+	for _, f := range fields {
+		if g, ok := f.Value.([]Field); ok {
+			writeFields(b, prefix+f.Key+".", g)
+
+			continue
+		}
+		b.WriteByte(' ')
+		b.WriteString(prefix + f.Key)
+		b.WriteByte('=')
+		b.WriteString(quoteValue(fmt.Sprint(consoleValue(f.Value))))
+	}
+}
+
+func quoteValue(s string) string {
+	if s == "" || strings.ContainsAny(s, " =\"\t\n") {
+		return strconv.Quote(s)
+	}
+
+	return s
+}
@@ -0,0 +1,157 @@
+package logger
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_appendFields(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		fields []Field
+		kv     []interface{}
+	}
+	tests := []struct {
+		name string
+		args args
+		want []Field
+	}{
+		{
+			name: "no key/values",
+			args: args{
+				fields: []Field{{Key: "a", Value: 1}},
+			},
+			want: []Field{{Key: "a", Value: 1}},
+		},
+		{
+			name: "key/value pairs",
+			args: args{
+				fields: []Field{{Key: "a", Value: 1}},
+				kv:     []interface{}{"b", "two", "c", 3.0},
+			},
+			want: []Field{{Key: "a", Value: 1}, {Key: "b", Value: "two"}, {Key: "c", Value: 3.0}},
+		},
+		{
+			name: "Field argument",
+			args: args{
+				kv: []interface{}{Field{Key: "a", Value: 1}, "b", 2},
+			},
+			want: []Field{{Key: "a", Value: 1}, {Key: "b", Value: 2}},
+		},
+		{
+			name: "missing value",
+			args: args{
+				kv: []interface{}{"a", 1, "b"},
+			},
+			want: []Field{{Key: "a", Value: 1}, {Key: badKey, Value: "b"}},
+		},
+		{
+			name: "non string key",
+			args: args{
+				kv: []interface{}{42, "a", 1},
+			},
+			want: []Field{{Key: badKey, Value: 42}, {Key: "a", Value: 1}},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := appendFields(tt.args.fields, tt.args.kv); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("appendFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_appendFields_copy(t *testing.T) {
+	t.Parallel()
+
+	base := make([]Field, 1, 10)
+	base[0] = Field{Key: "a", Value: 1}
+
+	f1 := appendFields(base, []interface{}{"b", 2})
+	f2 := appendFields(base, []interface{}{"c", 3})
+
+	if f1[1].Key != "b" {
+		t.Errorf("appendFields() shared backing array, got key %v, want %v", f1[1].Key, "b")
+	}
+	if f2[1].Key != "c" {
+		t.Errorf("appendFields() got key %v, want %v", f2[1].Key, "c")
+	}
+}
+
+func Test_payloadValue(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		v    interface{}
+		want interface{}
+	}{
+		{
+			name: "string",
+			v:    "value",
+			want: "value",
+		},
+		{
+			name: "error",
+			v:    errors.New("Bang"),
+			want: "Bang",
+		},
+		{
+			name: "group",
+			v:    []Field{{Key: "a", Value: 1}, {Key: "b", Value: []Field{{Key: "c", Value: errors.New("Bang")}}}},
+			want: map[string]interface{}{"a": 1, "b": map[string]interface{}{"c": "Bang"}},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := payloadValue(tt.v); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("payloadValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_formatFields(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		fields []Field
+		want   string
+	}{
+		{
+			name: "no fields",
+		},
+		{
+			name:   "simple",
+			fields: []Field{{Key: "order_id", Value: 123}, {Key: "tenant", Value: "acme"}},
+			want:   " order_id=123 tenant=acme",
+		},
+		{
+			name:   "quoted",
+			fields: []Field{{Key: "msg", Value: "hello world"}, {Key: "empty", Value: ""}, {Key: "err", Value: errors.New("a=b")}},
+			want:   ` msg="hello world" empty="" err="a=b"`,
+		},
+		{
+			name:   "group",
+			fields: []Field{{Key: "req", Value: []Field{{Key: "id", Value: 7}, {Key: "user", Value: []Field{{Key: "name", Value: "bob"}}}}}},
+			want:   " req.id=7 req.user.name=bob",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := formatFields(tt.fields); got != tt.want {
+				t.Errorf("formatFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,20 +5,30 @@ import (
 	"fmt"
 	"net/http"
 	"sync"
-	"time"
 
 	"cloud.google.com/go/logging"
-	"contrib.go.opencensus.io/exporter/stackdriver/propagation"
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/trace"
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"google.golang.org/grpc"
 )
 
+const reportedErrorEventType = "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"
+
 // GoogleCloudExporter implements exporting to Google Cloud Logging
 type GoogleCloudExporter struct {
-	projectID string
-	client    *logging.Client
-	opts      []logging.LoggerOption
-	logAll    bool
+	projectID      string
+	client         *logging.Client
+	opts           []logging.LoggerOption
+	stdout         logger
+	serviceContext *serviceContext
+	opOnce         sync.Once
+	opSink         *gcpSink
+	config
+}
+
+// serviceContext identifies the service in Cloud Error Reporting
+type serviceContext struct {
+	service string
+	version string
 }
 
 // NewGoogleCloudExporter returns a configured GoogleCloudExporter
@@ -27,7 +37,7 @@ func NewGoogleCloudExporter(client *logging.Client, projectID string, opts ...lo
 		projectID: projectID,
 		client:    client,
 		opts:      opts,
-		logAll:    true,
+		config:    config{logAll: true},
 	}
 }
 
@@ -39,87 +49,88 @@ func (e *GoogleCloudExporter) LogAll(v bool) *GoogleCloudExporter {
 	return e
 }
 
+// Repanic controls if a panic in the handler is propagated after it is logged. When false,
+// the panic is recovered and a 500 Internal Server Error is sent if the response has not
+// been started (default: false)
+func (e *GoogleCloudExporter) Repanic(v bool) *GoogleCloudExporter {
+	e.repanic = v
+
+	return e
+}
+
+// Options applies opts to the request logging of the exporter
+func (e *GoogleCloudExporter) Options(opts ...Option) *GoogleCloudExporter {
+	for _, opt := range opts {
+		opt(&e.config)
+	}
+
+	return e
+}
+
+// ErrorReporting formats logs written at Error or above so they are grouped in Cloud Error Reporting
+// under service and version. Each error log records a stack trace, and the request and user (the
+// "user" field set with SetRequestField) it occurred in. version may be empty.
+func (e *GoogleCloudExporter) ErrorReporting(service, version string) *GoogleCloudExporter {
+	e.serviceContext = &serviceContext{service: service, version: version}
+	e.errorStack = true
+
+	return e
+}
+
+// UnaryServerInterceptor returns a gRPC interceptor that injects a Logger into the context of unary calls,
+// and writes a request log for each call. The SeverityPolicy applies to the HTTP status equivalent to the
+// gRPC status code of the call.
+func (e *GoogleCloudExporter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return (&grpcHandler{sink: e.sink(), config: e.config}).unary
+}
+
+// StreamServerInterceptor returns a gRPC interceptor that injects a Logger into the context of streams,
+// and writes a request log for each stream.
+func (e *GoogleCloudExporter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return (&grpcHandler{sink: e.sink(), config: e.config}).stream
+}
+
+// StartOperation starts logging work done outside an HTTP request, such as a job or a message consumer.
+// Logs written with Ctx to the returned context are correlated by a log of the operation, written with
+// its duration and outcome when end is called. A non-nil err marks the operation as failed.
+func (e *GoogleCloudExporter) StartOperation(ctx context.Context, name string) (context.Context, func(err error)) {
+	// The Loggers of the Sink are created once, and shared by all operations
+	e.opOnce.Do(func() { e.opSink = e.sink() })
+
+	return e.startOperation(ctx, e.opSink, name)
+}
+
 // Middleware returns a middleware that exports logs to Google Cloud Logging
 func (e *GoogleCloudExporter) Middleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
-		return &gcpHandler{
-			next:         next,
-			parentLogger: e.client.Logger("request_parent_log", e.opts...),
-			childLogger:  e.client.Logger("request_child_log", e.opts...),
-			projectID:    e.projectID,
-			logAll:       e.logAll,
+		return &requestHandler{
+			next:   next,
+			sink:   e.sink(),
+			config: e.config,
 		}
 	}
 }
 
-type gcpHandler struct {
-	next         http.Handler
-	parentLogger logger
-	childLogger  logger
-	projectID    string
-	logAll       bool
-}
-
-func (g *gcpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	begin := time.Now()
-	traceID := gcpTraceIDFromRequest(r, g.projectID)
-	l := newGCPLogger(g.childLogger, traceID)
-	r = r.WithContext(newContext(r.Context(), l))
-	sw := &statusWriter{ResponseWriter: w}
-
-	g.next.ServeHTTP(sw, r)
-
-	l.mu.Lock()
-	logCount := l.logCount
-	maxSeverity := l.maxSeverity
-	l.mu.Unlock()
-
-	if !g.logAll && logCount == 0 {
-		return
-	}
-
-	// status code should also set the minimum maxSeverity to Error
-	if sw.Status() > 399 && maxSeverity < logging.Error {
-		maxSeverity = logging.Error
-	}
-
-	sc := trace.SpanFromContext(r.Context()).SpanContext()
-
-	g.parentLogger.Log(logging.Entry{
-		Timestamp:    begin,
-		Severity:     maxSeverity,
-		Trace:        traceID,
-		SpanID:       sc.SpanID().String(),
-		TraceSampled: sc.IsSampled(),
-		Payload: map[string]interface{}{
-			"message": "Parent Log Entry",
-		},
-		HTTPRequest: &logging.HTTPRequest{
-			Request:      r,
-			RequestSize:  requestSize(r.Header.Get("Content-Length")),
-			Latency:      time.Since(begin),
-			Status:       sw.Status(),
-			ResponseSize: sw.length,
-			RemoteIP:     r.Header.Get("X-Forwarded-For"),
-		},
-	})
-}
-
-// gcpTraceIDFromRequest formats a trace_id value for GCP Stackdriver
-func gcpTraceIDFromRequest(r *http.Request, projectID string) string {
-	var traceID string
-	if sc, ok := new(propagation.HTTPFormat).SpanContextFromRequest(r); ok {
-		traceID = sc.TraceID.String()
-	} else {
-		sc := trace.SpanFromContext(r.Context()).SpanContext()
-		if sc.IsValid() {
-			traceID = sc.TraceID().String()
-		} else {
-			_, span := otel.Tracer("").Start(r.Context(), r.URL.String())
-			traceID = span.SpanContext().TraceID().String()
+func (e *GoogleCloudExporter) sink() *gcpSink {
+	if e.stdout != nil {
+		return &gcpSink{
+			parentLogger:   e.stdout,
+			childLogger:    e.stdout,
+			projectID:      e.projectID,
+			serviceContext: e.serviceContext,
 		}
 	}
 
+	return &gcpSink{
+		parentLogger:   e.client.Logger("request_parent_log", e.opts...),
+		childLogger:    e.client.Logger("request_child_log", e.opts...),
+		projectID:      e.projectID,
+		serviceContext: e.serviceContext,
+	}
+}
+
+// gcpTraceID formats a trace_id value for GCP Stackdriver
+func gcpTraceID(projectID, traceID string) string {
 	return fmt.Sprintf("projects/%s/traces/%s", projectID, traceID)
 }
 
@@ -128,84 +139,122 @@ type logger interface {
 	Log(e logging.Entry)
 }
 
-type gcpLogger struct {
-	lg          logger
-	traceID     string
-	mu          sync.Mutex
-	maxSeverity logging.Severity
-	logCount    int
+// gcpSink writes Records as Google Cloud Logging entries
+type gcpSink struct {
+	parentLogger   logger
+	childLogger    logger
+	projectID      string
+	serviceContext *serviceContext
 }
 
-func newGCPLogger(lg logger, traceID string) *gcpLogger {
-	return &gcpLogger{
-		lg:      lg,
-		traceID: traceID,
+// WriteParent writes the request log
+func (s *gcpSink) WriteParent(rec *Record) {
+	var req *logging.HTTPRequest
+	if rec.Request != nil {
+		req = &logging.HTTPRequest{
+			Request:      rec.Request.Request,
+			RequestSize:  rec.Request.RequestSize,
+			Latency:      rec.Request.Latency,
+			Status:       rec.Request.Status,
+			ResponseSize: rec.Request.ResponseSize,
+			RemoteIP:     rec.Request.RemoteIP,
+		}
 	}
-}
-
-// Debug logs a debug message.
-func (l *gcpLogger) Debug(ctx context.Context, v interface{}) {
-	l.log(ctx, logging.Debug, v)
-}
 
-// Debugf logs a debug message with format.
-func (l *gcpLogger) Debugf(ctx context.Context, format string, v ...interface{}) {
-	l.log(ctx, logging.Debug, fmt.Sprintf(format, v...))
-}
-
-// Info logs a info message.
-func (l *gcpLogger) Info(ctx context.Context, v interface{}) {
-	l.log(ctx, logging.Info, v)
+	e := s.entry(rec, s.payload(rec))
+	e.HTTPRequest = req
+	if rec.Request != nil && rec.Request.Route != "" {
+		e.Labels = map[string]string{"route": rec.Request.Route}
+	}
+	if rec.Operation != "" {
+		e.Labels = map[string]string{"operation": rec.Operation}
+	}
+	s.parentLogger.Log(e)
 }
 
-// Infof logs a info message with format.
-func (l *gcpLogger) Infof(ctx context.Context, format string, v ...interface{}) {
-	l.log(ctx, logging.Info, fmt.Sprintf(format, v...))
+// WriteChild writes a log generated during the request
+func (s *gcpSink) WriteChild(rec *Record) {
+	payload := s.payload(rec)
+	if rec.Stack != "" || (s.serviceContext != nil && rec.Severity >= logging.Error) {
+		s.reportError(payload, rec)
+	}
+	s.childLogger.Log(s.entry(rec, payload))
 }
 
-// Warn logs a warning message.
-func (l *gcpLogger) Warn(ctx context.Context, v interface{}) {
-	l.log(ctx, logging.Warning, v)
-}
+// payload returns the JSON payload of rec
+func (s *gcpSink) payload(rec *Record) map[string]interface{} {
+	payload := make(map[string]interface{}, len(rec.Fields)+1)
+	for _, f := range rec.Fields {
+		payload[f.Key] = payloadValue(f.Value)
+	}
+	payload["message"] = payloadValue(rec.Message)
+	if err, ok := rec.Message.(error); ok {
+		if c, ok := errorChain(err); ok {
+			// Keep the message a string, and record the details of the chain alongside it
+			payload["message"] = chainMessage(c)
+			payload["error"] = chainPayload(c)
+		}
+	}
 
-// Warnf logs a warning message with format.
-func (l *gcpLogger) Warnf(ctx context.Context, format string, v ...interface{}) {
-	l.log(ctx, logging.Warning, fmt.Sprintf(format, v...))
+	return payload
 }
 
-// Error logs an error message.
-func (l *gcpLogger) Error(ctx context.Context, v interface{}) {
-	l.log(ctx, logging.Error, v)
-}
+// reportError formats the payload of rec as a ReportedErrorEvent, so it is picked up by Cloud Error Reporting
+func (s *gcpSink) reportError(payload map[string]interface{}, rec *Record) {
+	payload["@type"] = reportedErrorEventType
+	if rec.Stack != "" {
+		payload["stack_trace"] = fmt.Sprintf("%s\n\n%s", payload["message"], rec.Stack)
+	}
+	if s.serviceContext != nil {
+		sc := map[string]interface{}{"service": s.serviceContext.service}
+		if s.serviceContext.version != "" {
+			sc["version"] = s.serviceContext.version
+		}
+		payload["serviceContext"] = sc
+	}
 
-// Errorf logs an error message with format.
-func (l *gcpLogger) Errorf(ctx context.Context, format string, v ...interface{}) {
-	l.log(ctx, logging.Error, fmt.Sprintf(format, v...))
+	ctx := make(map[string]interface{})
+	if req := rec.Request; req != nil && req.Request != nil {
+		ctx["httpRequest"] = map[string]interface{}{
+			"method":    req.Request.Method,
+			"url":       req.Request.URL.String(),
+			"userAgent": req.Request.UserAgent(),
+			"referrer":  req.Request.Referer(),
+			"remoteIp":  req.RemoteIP,
+		}
+		if req.User != "" {
+			ctx["user"] = req.User
+		}
+	}
+	if rec.Stack == "" && rec.Source != nil {
+		// Without a stack trace, errors are grouped by where they were logged
+		ctx["reportLocation"] = map[string]interface{}{
+			"filePath":     rec.Source.File,
+			"lineNumber":   rec.Source.Line,
+			"functionName": rec.Source.Function,
+		}
+	}
+	if len(ctx) != 0 {
+		payload["context"] = ctx
+	}
 }
 
-func (l *gcpLogger) log(ctx context.Context, severity logging.Severity, p interface{}) {
-	l.mu.Lock()
-	if l.maxSeverity < severity {
-		l.maxSeverity = severity
+func (s *gcpSink) entry(rec *Record, payload map[string]interface{}) logging.Entry {
+	e := logging.Entry{
+		Timestamp:    rec.Time,
+		Severity:     rec.Severity,
+		Payload:      payload,
+		Trace:        gcpTraceID(s.projectID, rec.TraceID),
+		SpanID:       rec.SpanID,
+		TraceSampled: rec.TraceSampled,
 	}
-	l.logCount++
-	l.mu.Unlock()
-
-	if err, ok := p.(error); ok {
-		p = err.Error()
+	if rec.Source != nil {
+		e.SourceLocation = &loggingpb.LogEntrySourceLocation{
+			File:     rec.Source.File,
+			Line:     int64(rec.Source.Line),
+			Function: rec.Source.Function,
+		}
 	}
 
-	span := trace.SpanFromContext(ctx)
-
-	l.lg.Log(
-		logging.Entry{
-			Payload: map[string]interface{}{
-				"message": p,
-			},
-			Severity:     severity,
-			Trace:        l.traceID,
-			SpanID:       span.SpanContext().SpanID().String(),
-			TraceSampled: span.SpanContext().IsSampled(),
-		},
-	)
+	return e
 }
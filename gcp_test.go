@@ -2,19 +2,20 @@ package logger
 
 import (
 	"bytes"
-	"context"
 	"errors"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
-	"net/url"
 	"os"
 	"reflect"
 	"testing"
+	"time"
 
 	"cloud.google.com/go/logging"
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	goerrors "github.com/go-playground/errors/v5"
 	"github.com/go-test/deep"
-	"go.opentelemetry.io/otel"
-	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 func TestNewGoogleCloudExporter(t *testing.T) {
@@ -41,7 +42,7 @@ func TestNewGoogleCloudExporter(t *testing.T) {
 				projectID: "My Project ID",
 				client:    &logging.Client{},
 				opts:      []logging.LoggerOption{logging.ConcurrentWriteLimit(5)},
-				logAll:    true,
+				config:    config{logAll: true},
 			},
 		},
 	}
@@ -76,7 +77,7 @@ func TestGoogleCloudExporter_LogAll(t *testing.T) {
 				v: true,
 			},
 			want: &GoogleCloudExporter{
-				logAll: true,
+				config: config{logAll: true},
 			},
 		},
 		{
@@ -88,7 +89,7 @@ func TestGoogleCloudExporter_LogAll(t *testing.T) {
 				v: false,
 			},
 			want: &GoogleCloudExporter{
-				logAll: false,
+				config: config{logAll: false},
 			},
 		},
 	}
@@ -97,7 +98,7 @@ func TestGoogleCloudExporter_LogAll(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 			e := &GoogleCloudExporter{
-				logAll: tt.fields.logAll,
+				config: config{logAll: tt.fields.logAll},
 			}
 			if got := e.LogAll(tt.args.v); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("GoogleCloudExporter.LogAll() = %v, want %v", got, tt.want)
@@ -106,6 +107,31 @@ func TestGoogleCloudExporter_LogAll(t *testing.T) {
 	}
 }
 
+func TestGoogleCloudExporter_Repanic(t *testing.T) {
+	t.Parallel()
+
+	for _, v := range []bool{true, false} {
+		e := &GoogleCloudExporter{config: config{repanic: !v}}
+		want := &GoogleCloudExporter{config: config{repanic: v}}
+		if got := e.Repanic(v); !reflect.DeepEqual(got, want) {
+			t.Errorf("GoogleCloudExporter.Repanic() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestGoogleCloudExporter_Options(t *testing.T) {
+	t.Parallel()
+
+	e := &GoogleCloudExporter{}
+	want := &GoogleCloudExporter{config: config{propagators: []Propagator{W3CPropagator{}, B3MultiPropagator{}}, buffer: true}}
+	if got := e.Options(WithPropagators(W3CPropagator{}, B3MultiPropagator{}), WithBuffer(true)); !reflect.DeepEqual(got, want) {
+		t.Errorf("GoogleCloudExporter.Options() = %v, want %v", got, want)
+	}
+	if got := e.Options(WithPropagators()); got.propagators != nil {
+		t.Errorf("GoogleCloudExporter.Options() propagators = %v, want nil", got.propagators)
+	}
+}
+
 func TestGoogleCloudExporter_Middleware(t *testing.T) {
 	disableMetaServertest(t)
 
@@ -132,12 +158,14 @@ func TestGoogleCloudExporter_Middleware(t *testing.T) {
 				client := &logging.Client{}
 				opts := []logging.LoggerOption{logging.ConcurrentWriteLimit(5)}
 
-				return &gcpHandler{
-					next:         next,
-					parentLogger: client.Logger("request_parent_log", opts...),
-					childLogger:  client.Logger("request_child_log", opts...),
-					projectID:    "My other project",
-					logAll:       true,
+				return &requestHandler{
+					next: next,
+					sink: &gcpSink{
+						parentLogger: client.Logger("request_parent_log", opts...),
+						childLogger:  client.Logger("request_child_log", opts...),
+						projectID:    "My other project",
+					},
+					config: config{logAll: true},
 				}
 			},
 		},
@@ -150,7 +178,7 @@ func TestGoogleCloudExporter_Middleware(t *testing.T) {
 				projectID: tt.fields.projectID,
 				client:    tt.fields.client,
 				opts:      tt.fields.opts,
-				logAll:    tt.fields.logAll,
+				config:    config{logAll: tt.fields.logAll},
 			}
 			got := e.Middleware()(next)
 			if diff := deep.Equal(got, tt.want(next)); diff != nil {
@@ -160,311 +188,301 @@ func TestGoogleCloudExporter_Middleware(t *testing.T) {
 	}
 }
 
-func Test_gcpHandler_ServeHTTP(t *testing.T) {
+func Test_gcpTraceID(t *testing.T) {
 	t.Parallel()
 
 	type args struct {
-		status int
-		logs   int
-		level  logging.Severity
-	}
-	type fields struct {
 		projectID string
-		logAll    bool
+		traceID   string
 	}
 	tests := []struct {
-		name      string
-		fields    fields
-		args      args
-		wantLevel logging.Severity
+		name string
+		args args
+		want string
 	}{
 		{
-			name: "logAll=true",
-			fields: fields{
-				projectID: "my-big-project",
-				logAll:    true,
-			},
-			args: args{
-				status: http.StatusOK,
-				logs:   1,
-				level:  logging.Info,
-			},
-			wantLevel: logging.Info,
-		},
-		{
-			name: "logAll=true no logging",
-			fields: fields{
-				projectID: "my-big-project",
-				logAll:    true,
-			},
-			args: args{
-				status: http.StatusOK,
-			},
-			wantLevel: logging.Default,
-		},
-		{
-			name: "logAll=false no logging",
-			fields: fields{
-				projectID: "my-big-project",
-			},
+			name: "format",
 			args: args{
-				status: http.StatusOK,
-			},
-		},
-		{
-			name: "logAll=false with logging",
-			fields: fields{
-				projectID: "my-bigger-project",
-			},
-			args: args{
-				status: http.StatusOK,
-				logs:   1,
-				level:  logging.Warning,
-			},
-			wantLevel: logging.Warning,
-		},
-		{
-			name: "logAll=true no logging",
-			fields: fields{
-				projectID: "my-big-project",
-				logAll:    true,
-			},
-			args: args{
-				status: http.StatusInternalServerError,
+				projectID: "my-project",
+				traceID:   "105445aa7843bc8bf206b12000100000",
 			},
-			wantLevel: logging.Error,
+			want: "projects/my-project/traces/105445aa7843bc8bf206b12000100000",
 		},
 	}
 	for _, tt := range tests {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-
-			var handlerCalled bool
-			var traceID string
-			l := &captureLogger{}
-			handler := &gcpHandler{
-				parentLogger: l,
-				childLogger:  &captureLogger{},
-				projectID:    tt.fields.projectID,
-				logAll:       tt.fields.logAll,
-				next: http.HandlerFunc(
-					func(w http.ResponseWriter, r *http.Request) {
-						for i := 0; i < tt.args.logs; i++ {
-							switch tt.args.level {
-							case logging.Info:
-								Req(r).Info("some log")
-							case logging.Warning:
-								Req(r).Warn("some log")
-							case logging.Error:
-								Req(r).Error("some log")
-							default:
-							}
-						}
-
-						l := Req(r)
-						if l, ok := l.lg.(*gcpLogger); ok {
-							traceID = l.traceID
-						} else {
-							t.Fatalf("Req() = %v, wanted: %T", l, &gcpLogger{})
-						}
-
-						w.WriteHeader(tt.args.status)
-						handlerCalled = true
-					},
-				),
-			}
-
-			w := httptest.NewRecorder()
-			r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
-			handler.ServeHTTP(w, r)
-
-			if !handlerCalled {
-				t.Errorf("Failed to call handler")
-			}
-			if tt.args.logs == 0 {
-				return
-			}
-			if l.e.Severity != tt.wantLevel {
-				t.Errorf("Severity = %v, want %v", l.e.Severity, tt.wantLevel)
-			}
-			if l.e.Trace != traceID {
-				t.Errorf("Trace = %v, want %v", l.e.Trace, traceID)
-			}
-			if pl, ok := l.e.Payload.(map[string]interface{}); ok {
-				if m, ok := pl["message"].(string); ok {
-					if m != "Parent Log Entry" {
-						t.Errorf("Message = %v, want %v", m, "Parent Log Entry")
-					}
-				} else {
-					t.Fatalf("Message = %T, want %T", pl["message"], "")
-				}
-			} else {
-				t.Fatalf("Payload = %T, want %T", l.e.Payload, map[string]interface{}{})
-			}
-			if l.e.HTTPRequest.Status != tt.args.status {
-				t.Errorf("Status = %v, want %v", l.e.HTTPRequest.Status, tt.args.status)
+			if got := gcpTraceID(tt.args.projectID, tt.args.traceID); got != tt.want {
+				t.Errorf("gcpTraceID() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func Test_gcpTraceIDFromRequest(t *testing.T) {
+func Test_gcpSink_WriteChild(t *testing.T) {
+	t.Parallel()
+
 	type args struct {
-		mockReq   func(traceStr string) (*http.Request, string)
-		projectID string
+		v interface{}
 	}
 	tests := []struct {
-		name            string
-		args            args
-		wantTracePrefix string
-		wantTraceStr    string
+		name string
+		args args
+		want string
 	}{
-		// The order these are significant
-		{
-			// This test relies on the global tracing provider NOT being set
-			name: "no trace in request",
-			args: args{
-				mockReq: func(wantTraceStr string) (*http.Request, string) {
-					return &http.Request{URL: &url.URL{}}, wantTraceStr
-				},
-				projectID: "my-project",
-			},
-			wantTracePrefix: "projects/my-project/traces/",
-			wantTraceStr:    "00000000000000000000000000000000",
-		},
 		{
-			// This test sets the global tracing provider (I don't think this can be un-done)
-			name: "with trace in request",
+			name: "String",
 			args: args{
-				mockReq: func(_ string) (r *http.Request, traceStr string) {
-					otel.SetTracerProvider(sdktrace.NewTracerProvider())
-					ctx, span := otel.Tracer("test/examples").Start(context.Background(), "test trace")
-
-					r = httptest.NewRequest(http.MethodGet, "/", http.NoBody)
-					r = r.WithContext(ctx)
-
-					return r, span.SpanContext().TraceID().String()
-				},
-				projectID: "my-project",
+				v: "Message",
 			},
-			wantTracePrefix: "projects/my-project/traces/",
+			want: "Message",
 		},
 		{
-			// With the global tracing provider set, this test shows that
-			// trace Propagation is a higher priority then trace in request context
-			name: "with propagation span in headers",
+			name: "Error",
 			args: args{
-				mockReq: func(wantTraceStr string) (r *http.Request, traceStr string) {
-					r = httptest.NewRequest(http.MethodGet, "/", http.NoBody)
-					r.Header.Add("X-Cloud-Trace-Context", wantTraceStr+"/1;o=1")
-
-					return r, wantTraceStr
-				},
-				projectID: "my-project",
+				v: errors.New("Message"),
 			},
-			wantTracePrefix: "projects/my-project/traces/",
-			wantTraceStr:    "105445aa7843bc8bf206b12000100000",
+			want: "Message",
 		},
 	}
 	for _, tt := range tests {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
-			r, traceStr := tt.args.mockReq(tt.wantTraceStr)
-			want := tt.wantTracePrefix + traceStr
-			if got := gcpTraceIDFromRequest(r, tt.args.projectID); got != want {
-				t.Errorf("traceIDFromRequest() = %v, want %v", got, want)
+			t.Parallel()
+
+			for _, severity := range []logging.Severity{logging.Debug, logging.Info, logging.Warning, logging.Error} {
+				c := &captureLogger{}
+				s := &gcpSink{
+					childLogger: c,
+					projectID:   "my-project",
+				}
+				s.WriteChild(&Record{
+					Severity: severity,
+					Message:  tt.args.v,
+					Fields:   []Field{{Key: "order_id", Value: 123}},
+					TraceID:  "105445aa7843bc8bf206b12000100000",
+					SpanID:   "0000000000000001",
+				})
+
+				want := logging.Entry{
+					Severity: severity,
+					Payload: map[string]interface{}{
+						"message":  tt.want,
+						"order_id": 123,
+					},
+					Trace:  "projects/my-project/traces/105445aa7843bc8bf206b12000100000",
+					SpanID: "0000000000000001",
+				}
+				if diff := deep.Equal(c.e, want); diff != nil {
+					t.Errorf("gcpSink.WriteChild() = %v", diff)
+				}
 			}
 		})
 	}
 }
 
-func Test_newGCPLogger(t *testing.T) {
+func Test_gcpSink_WriteParent(t *testing.T) {
 	t.Parallel()
 
-	type args struct {
-		lg      *logging.Logger
-		traceID string
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	c := &captureLogger{}
+	s := &gcpSink{
+		parentLogger: c,
+		projectID:    "my-project",
 	}
-	tests := []struct {
-		name string
-		args args
-		want ctxLogger
-	}{
-		{
-			name: "new",
-			args: args{
-				lg:      &logging.Logger{},
-				traceID: "hello",
-			},
-			want: &gcpLogger{
-				lg:      &logging.Logger{},
-				traceID: "hello",
-			},
+	s.WriteParent(&Record{
+		Severity:     logging.Warning,
+		Message:      "Parent Log Entry",
+		TraceID:      "105445aa7843bc8bf206b12000100000",
+		TraceSampled: true,
+		Request: &HTTPRequest{
+			Request:      r,
+			RequestSize:  10,
+			Status:       http.StatusNotFound,
+			ResponseSize: 20,
+			Latency:      time.Second,
+			RemoteIP:     "192.168.1.1",
+		},
+	})
+
+	want := logging.Entry{
+		Severity: logging.Warning,
+		Payload: map[string]interface{}{
+			"message": "Parent Log Entry",
+		},
+		Trace:        "projects/my-project/traces/105445aa7843bc8bf206b12000100000",
+		TraceSampled: true,
+		HTTPRequest: &logging.HTTPRequest{
+			Request:      r,
+			RequestSize:  10,
+			Status:       http.StatusNotFound,
+			ResponseSize: 20,
+			Latency:      time.Second,
+			RemoteIP:     "192.168.1.1",
 		},
 	}
-	for _, tt := range tests {
-		tt := tt
-		t.Run(tt.name, func(t *testing.T) {
-			t.Parallel()
-			if got := newGCPLogger(tt.args.lg, tt.args.traceID); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("New() = %v, want %v", got, tt.want)
-			}
-		})
+	if diff := deep.Equal(c.e, want); diff != nil {
+		t.Errorf("gcpSink.WriteParent() = %v", diff)
 	}
 }
 
-func Test_gcpLogger(t *testing.T) {
+func Test_gcpSink_WriteParent_route(t *testing.T) {
 	t.Parallel()
 
-	type args struct {
-		format string
-		v      []interface{}
-		v2     interface{}
+	c := &captureLogger{}
+	s := &gcpSink{parentLogger: c}
+	s.WriteParent(&Record{
+		Request: &HTTPRequest{
+			Request: httptest.NewRequest(http.MethodGet, "/users/123", http.NoBody),
+			Route:   "/users/{id}",
+		},
+	})
+
+	if diff := deep.Equal(c.e.Labels, map[string]string{"route": "/users/{id}"}); diff != nil {
+		t.Errorf("gcpSink.WriteParent() Labels = %v", diff)
+	}
+}
+
+func Test_gcpSink_WriteChild_stack(t *testing.T) {
+	t.Parallel()
+
+	c := &captureLogger{}
+	s := &gcpSink{
+		childLogger: c,
+		projectID:   "my-project",
+	}
+	s.WriteChild(&Record{
+		Severity: logging.Error,
+		Message:  "panic: boom",
+		TraceID:  "105445aa7843bc8bf206b12000100000",
+		Stack:    "goroutine 1 [running]:",
+	})
+
+	want := logging.Entry{
+		Severity: logging.Error,
+		Payload: map[string]interface{}{
+			"message":     "panic: boom",
+			"@type":       "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent",
+			"stack_trace": "panic: boom\n\ngoroutine 1 [running]:",
+		},
+		Trace: "projects/my-project/traces/105445aa7843bc8bf206b12000100000",
+	}
+	if diff := deep.Equal(c.e, want); diff != nil {
+		t.Errorf("gcpSink.WriteChild() = %v", diff)
+	}
+}
+
+func Test_gcpSink_WriteChild_source(t *testing.T) {
+	t.Parallel()
+
+	c := &captureLogger{}
+	s := &gcpSink{childLogger: c}
+	s.WriteChild(&Record{
+		Severity: logging.Info,
+		Message:  "Message",
+		Source:   &slog.Source{Function: "main.main", File: "/src/app/main.go", Line: 42},
+	})
+
+	want := &loggingpb.LogEntrySourceLocation{File: "/src/app/main.go", Line: 42, Function: "main.main"}
+	if diff := deep.Equal(c.e.SourceLocation, want); diff != nil {
+		t.Errorf("gcpSink.WriteChild() SourceLocation = %v", diff)
+	}
+}
+
+func Test_gcpSink_WriteChild_errorChain(t *testing.T) {
+	t.Parallel()
+
+	c := &captureLogger{}
+	s := &gcpSink{childLogger: c}
+	s.WriteChild(&Record{
+		Severity: logging.Error,
+		Message:  goerrors.Wrap(io.EOF, "read body"),
+		Fields:   []Field{{Key: "cause", Value: goerrors.New("boom")}},
+	})
+
+	payload, _ := c.e.Payload.(map[string]interface{})
+	if got := payload["message"]; got != "read body: EOF" {
+		t.Errorf("gcpSink.WriteChild() message = %v, want %v", got, "read body: EOF")
+	}
+	if got, _ := payload["error"].(map[string]interface{}); got["message"] != "read body: EOF" || got["chain"] == nil {
+		t.Errorf("gcpSink.WriteChild() error = %v, want the chain", payload["error"])
+	}
+	if got, _ := payload["cause"].(map[string]interface{}); got["message"] != "boom" || got["chain"] == nil {
+		t.Errorf("gcpSink.WriteChild() cause = %v, want the chain", payload["cause"])
 	}
+}
+
+func Test_gcpSink_WriteChild_errorReporting(t *testing.T) {
+	t.Parallel()
+
+	r := httptest.NewRequest(http.MethodGet, "/orders", http.NoBody)
+	src := &slog.Source{Function: "main.main", File: "/src/app/main.go", Line: 42}
+
 	tests := []struct {
-		name       string
-		args       args
-		wantDebug  string
-		wantDebugf string
-		wantInfo   string
-		wantInfof  string
-		wantWarn   string
-		wantWarnf  string
-		wantError  string
-		wantErrorf string
+		name           string
+		serviceContext *serviceContext
+		rec            *Record
+		want           map[string]interface{}
 	}{
 		{
-			name: "Strings",
-			args: args{
-				format: "Formatted %s",
-				v:      []interface{}{"Message"},
-				v2:     "Message",
+			name:           "error with stack",
+			serviceContext: &serviceContext{service: "checkout", version: "v1"},
+			rec: &Record{
+				Severity: logging.Error,
+				Message:  "failed",
+				Request:  &HTTPRequest{Request: r, RemoteIP: "192.0.2.1", User: "alice"},
+				Stack:    "goroutine 1 [running]:",
+				Source:   src,
+			},
+			want: map[string]interface{}{
+				"message":        "failed",
+				"@type":          reportedErrorEventType,
+				"stack_trace":    "failed\n\ngoroutine 1 [running]:",
+				"serviceContext": map[string]interface{}{"service": "checkout", "version": "v1"},
+				"context": map[string]interface{}{
+					"httpRequest": map[string]interface{}{
+						"method":    "GET",
+						"url":       "/orders",
+						"userAgent": "",
+						"referrer":  "",
+						"remoteIp":  "192.0.2.1",
+					},
+					"user": "alice",
+				},
 			},
-			wantDebug:  "Message",
-			wantDebugf: "Formatted Message",
-			wantInfo:   "Message",
-			wantInfof:  "Formatted Message",
-			wantWarn:   "Message",
-			wantWarnf:  "Formatted Message",
-			wantError:  "Message",
-			wantErrorf: "Formatted Message",
 		},
 		{
-			name: "String & Error",
-			args: args{
-				format: "Formatted %s",
-				v:      []interface{}{"Message"},
-				v2:     errors.New("Message"),
+			name:           "critical without stack",
+			serviceContext: &serviceContext{service: "checkout"},
+			rec: &Record{
+				Severity: logging.Critical,
+				Message:  "failed",
+				Source:   src,
 			},
-			wantDebug:  "Message",
-			wantDebugf: "Formatted Message",
-			wantInfo:   "Message",
-			wantInfof:  "Formatted Message",
-			wantWarn:   "Message",
-			wantWarnf:  "Formatted Message",
-			wantError:  "Message",
-			wantErrorf: "Formatted Message",
+			want: map[string]interface{}{
+				"message":        "failed",
+				"@type":          reportedErrorEventType,
+				"serviceContext": map[string]interface{}{"service": "checkout"},
+				"context": map[string]interface{}{
+					"reportLocation": map[string]interface{}{
+						"filePath":     "/src/app/main.go",
+						"lineNumber":   42,
+						"functionName": "main.main",
+					},
+				},
+			},
+		},
+		{
+			name:           "warning",
+			serviceContext: &serviceContext{service: "checkout"},
+			rec:            &Record{Severity: logging.Warning, Message: "slow"},
+			want:           map[string]interface{}{"message": "slow"},
+		},
+		{
+			name: "disabled",
+			rec:  &Record{Severity: logging.Error, Message: "failed"},
+			want: map[string]interface{}{"message": "failed"},
 		},
 	}
 	for _, tt := range tests {
@@ -472,62 +490,12 @@ func Test_gcpLogger(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			ctx := context.Background()
-			var buf bytes.Buffer
-
-			l := &gcpLogger{
-				lg: &testLogger{
-					buf: &buf,
-				},
-			}
-
-			l.Debug(ctx, tt.args.v2)
-			if s := buf.String(); s != tt.wantDebug {
-				t.Errorf("stdErrLogger.Debug() value = %v, wantValue %v", s, tt.wantDebug)
-			}
-			buf.Reset()
-
-			l.Debugf(ctx, tt.args.format, tt.args.v...)
-			if s := buf.String(); s != tt.wantDebugf {
-				t.Errorf("stdErrLogger.Debug() value = %v, wantValue %v", s, tt.wantDebugf)
-			}
-			buf.Reset()
-
-			l.Info(ctx, tt.args.v2)
-			if s := buf.String(); s != tt.wantInfo {
-				t.Errorf("stdErrLogger.Info() value = %v, wantValue %v", s, tt.wantInfo)
-			}
-			buf.Reset()
-
-			l.Infof(ctx, tt.args.format, tt.args.v...)
-			if s := buf.String(); s != tt.wantInfof {
-				t.Errorf("stdErrLogger.Info() value = %v, wantValue %v", s, tt.wantInfof)
-			}
-			buf.Reset()
-
-			l.Warn(ctx, tt.args.v2)
-			if s := buf.String(); s != tt.wantWarn {
-				t.Errorf("stdErrLogger.Warn() value = %v, wantValue %v", s, tt.wantWarn)
-			}
-			buf.Reset()
-
-			l.Warnf(ctx, tt.args.format, tt.args.v...)
-			if s := buf.String(); s != tt.wantWarnf {
-				t.Errorf("stdErrLogger.Warn() value = %v, wantValue %v", s, tt.wantWarnf)
-			}
-			buf.Reset()
-
-			l.Error(ctx, tt.args.v2)
-			if s := buf.String(); s != tt.wantError {
-				t.Errorf("stdErrLogger.Error() value = %v, wantValue %v", s, tt.wantError)
-			}
-			buf.Reset()
-
-			l.Errorf(ctx, tt.args.format, tt.args.v...)
-			if s := buf.String(); s != tt.wantErrorf {
-				t.Errorf("stdErrLogger.Error() value = %v, wantValue %v", s, tt.wantErrorf)
+			c := &captureLogger{}
+			s := &gcpSink{childLogger: c, serviceContext: tt.serviceContext}
+			s.WriteChild(tt.rec)
+			if diff := deep.Equal(c.e.Payload, tt.want); diff != nil {
+				t.Errorf("gcpSink.WriteChild() Payload = %v", diff)
 			}
-			buf.Reset()
 		})
 	}
 }
@@ -0,0 +1,279 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging"
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"github.com/go-test/deep"
+)
+
+func TestNewGoogleCloudJSONExporter(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	want := &GoogleCloudExporter{
+		projectID: "My Project ID",
+		stdout:    &jsonLogger{w: &buf},
+		config:    config{logAll: true},
+	}
+	if got := NewGoogleCloudJSONExporter(&buf, "My Project ID"); !reflect.DeepEqual(got, want) {
+		t.Errorf("NewGoogleCloudJSONExporter() = %v, want %v", got, want)
+	}
+}
+
+func Test_jsonEntry(t *testing.T) {
+	t.Parallel()
+
+	ts := time.Date(2023, 8, 10, 12, 30, 0, 500, time.UTC)
+	r := httptest.NewRequest(http.MethodPost, "http://example.com/orders?id=1", http.NoBody)
+	r.Header.Set("User-Agent", "test-agent")
+	r.Header.Set("Referer", "http://example.com/")
+
+	tests := []struct {
+		name  string
+		entry logging.Entry
+		want  map[string]interface{}
+	}{
+		{
+			name: "child entry",
+			entry: logging.Entry{
+				Timestamp:    ts,
+				Severity:     logging.Warning,
+				Trace:        "projects/my-project/traces/105445aa7843bc8bf206b12000100000",
+				SpanID:       "0000000000000001",
+				TraceSampled: true,
+				Payload: map[string]interface{}{
+					"message":  "Message",
+					"order_id": 123,
+				},
+			},
+			want: map[string]interface{}{
+				"time":                                 "2023-08-10T12:30:00.0000005Z",
+				"severity":                             "WARNING",
+				"message":                              "Message",
+				"order_id":                             123,
+				"logging.googleapis.com/trace":         "projects/my-project/traces/105445aa7843bc8bf206b12000100000",
+				"logging.googleapis.com/spanId":        "0000000000000001",
+				"logging.googleapis.com/trace_sampled": true,
+			},
+		},
+		{
+			name: "source location",
+			entry: logging.Entry{
+				Timestamp: ts,
+				Severity:  logging.Info,
+				Payload:   "Message",
+				SourceLocation: &loggingpb.LogEntrySourceLocation{
+					File:     "/src/app/main.go",
+					Line:     42,
+					Function: "main.main",
+				},
+			},
+			want: map[string]interface{}{
+				"time":                                 "2023-08-10T12:30:00.0000005Z",
+				"severity":                             "INFO",
+				"message":                              "Message",
+				"logging.googleapis.com/trace_sampled": false,
+				"logging.googleapis.com/sourceLocation": map[string]interface{}{
+					"file":     "/src/app/main.go",
+					"line":     "42",
+					"function": "main.main",
+				},
+			},
+		},
+		{
+			name: "parent entry",
+			entry: logging.Entry{
+				Timestamp: ts,
+				Severity:  logging.Error,
+				Payload:   "Parent Log Entry",
+				Labels:    map[string]string{"a": "b"},
+				HTTPRequest: &logging.HTTPRequest{
+					Request:      r,
+					RequestSize:  10,
+					Status:       http.StatusInternalServerError,
+					ResponseSize: 20,
+					Latency:      1500 * time.Millisecond,
+					LocalIP:      "10.0.0.1",
+					RemoteIP:     "192.168.1.1",
+				},
+			},
+			want: map[string]interface{}{
+				"time":                                 "2023-08-10T12:30:00.0000005Z",
+				"severity":                             "ERROR",
+				"message":                              "Parent Log Entry",
+				"logging.googleapis.com/trace_sampled": false,
+				"logging.googleapis.com/labels":        map[string]string{"a": "b"},
+				"httpRequest": map[string]interface{}{
+					"requestMethod": "POST",
+					"requestUrl":    "http://example.com/orders?id=1",
+					"requestSize":   "10",
+					"status":        500,
+					"responseSize":  "20",
+					"userAgent":     "test-agent",
+					"remoteIp":      "192.168.1.1",
+					"serverIp":      "10.0.0.1",
+					"referer":       "http://example.com/",
+					"protocol":      "HTTP/1.1",
+					"latency":       "1.5s",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if diff := deep.Equal(jsonEntry(tt.entry), tt.want); diff != nil {
+				t.Errorf("jsonEntry() = %v", diff)
+			}
+		})
+	}
+}
+
+func Test_jsonLogger_Log(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	l := &jsonLogger{w: &buf}
+
+	l.Log(logging.Entry{Payload: map[string]interface{}{"message": "one"}})
+	l.Log(logging.Entry{Payload: map[string]interface{}{"message": "two", "bad": make(chan int)}})
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("jsonLogger.Log() lines = %d, want %d", len(lines), 2)
+	}
+	for i, want := range []string{"one", "two"} {
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(lines[i]), &m); err != nil {
+			t.Fatalf("json.Unmarshal() error = %v", err)
+		}
+		if msg, _ := m["message"].(string); !strings.Contains(msg, want) {
+			t.Errorf("message = %v, want to contain %v", msg, want)
+		}
+	}
+}
+
+func TestGoogleCloudJSONExporter_Middleware(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	h := NewGoogleCloudJSONExporter(&buf, "my-project").Middleware()(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			Req(r).Errorw("failed", "err", errors.New("Bang"))
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	))
+
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	r.Header.Add("X-Cloud-Trace-Context", "105445aa7843bc8bf206b12000100000/1;o=1")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	var entries []map[string]interface{}
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var m map[string]interface{}
+		if err := dec.Decode(&m); err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+		entries = append(entries, m)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("entries = %d, want %d", len(entries), 2)
+	}
+
+	child, parent := entries[0], entries[1]
+	wantTrace := "projects/my-project/traces/105445aa7843bc8bf206b12000100000"
+	for _, e := range entries {
+		if e["logging.googleapis.com/trace"] != wantTrace {
+			t.Errorf("trace = %v, want %v", e["logging.googleapis.com/trace"], wantTrace)
+		}
+		if e["severity"] != "ERROR" {
+			t.Errorf("severity = %v, want %v", e["severity"], "ERROR")
+		}
+	}
+	if child["err"] != "Bang" {
+		t.Errorf("err = %v, want %v", child["err"], "Bang")
+	}
+	if _, ok := child["httpRequest"]; ok {
+		t.Errorf("child httpRequest = %v, want none", child["httpRequest"])
+	}
+	req, ok := parent["httpRequest"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("parent httpRequest = %T, want %T", parent["httpRequest"], map[string]interface{}{})
+	}
+	if req["status"] != float64(http.StatusBadGateway) {
+		t.Errorf("status = %v, want %v", req["status"], http.StatusBadGateway)
+	}
+}
+
+func TestGoogleCloudJSONExporter_ErrorReporting(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	h := NewGoogleCloudJSONExporter(&buf, "my-project").
+		ErrorReporting("checkout", "v1.2.3").
+		Middleware()(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		l := Req(r)
+		l.SetRequestField("user", "alice")
+		l.Warn("slow")
+		l.Error("failed")
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/orders?id=1", http.NoBody)
+	r.Header.Set("User-Agent", "test-agent")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	var entries []map[string]interface{}
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var m map[string]interface{}
+		if err := dec.Decode(&m); err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+		entries = append(entries, m)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("entries = %d, want %d", len(entries), 3)
+	}
+
+	warn, child, parent := entries[0], entries[1], entries[2]
+	for _, e := range []map[string]interface{}{warn, parent} {
+		if _, ok := e["@type"]; ok {
+			t.Errorf("%v @type = %v, want none", e["message"], e["@type"])
+		}
+	}
+	if child["@type"] != reportedErrorEventType {
+		t.Errorf("@type = %v, want %v", child["@type"], reportedErrorEventType)
+	}
+	if diff := deep.Equal(child["serviceContext"], map[string]interface{}{"service": "checkout", "version": "v1.2.3"}); diff != nil {
+		t.Errorf("serviceContext = %v", diff)
+	}
+	wantContext := map[string]interface{}{
+		"httpRequest": map[string]interface{}{
+			"method":    "GET",
+			"url":       "/orders?id=1",
+			"userAgent": "test-agent",
+			"referrer":  "",
+			"remoteIp":  "192.0.2.1",
+		},
+		"user": "alice",
+	}
+	if diff := deep.Equal(child["context"], wantContext); diff != nil {
+		t.Errorf("context = %v", diff)
+	}
+	stack, _ := child["stack_trace"].(string)
+	if want := "failed\n\ngoroutine 1 [running]:\ngithub.com/jtwatson/logger.TestGoogleCloudJSONExporter_ErrorReporting.func1(...)\n"; !strings.HasPrefix(stack, want) {
+		t.Errorf("stack_trace = %v, want prefix %v", stack, want)
+	}
+}
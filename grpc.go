@@ -0,0 +1,315 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.20.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// grpcHandler logs gRPC calls the same way requestHandler logs HTTP requests. The request log of a call
+// records the method, status code, peer address, latency and the number of messages sent and received.
+// The Exclusions, Sampler and MessageFunc see a call as a POST request to its full method, with the
+// metadata as headers, as it is sent over HTTP/2.
+type grpcHandler struct {
+	sink Sink
+	config
+}
+
+// unary is a grpc.UnaryServerInterceptor
+func (h *grpcHandler) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	c := h.start(ctx, info.FullMethod)
+	c.received.Store(1)
+	defer func() {
+		err = c.end(recover(), err)
+	}()
+
+	resp, err = handler(c.ctx, req)
+	if err == nil {
+		c.sent.Store(1)
+	}
+
+	return resp, err
+}
+
+// stream is a grpc.StreamServerInterceptor
+func (h *grpcHandler) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	c := h.start(ss.Context(), info.FullMethod)
+	defer func() {
+		err = c.end(recover(), err)
+	}()
+
+	return handler(srv, &loggedStream{ServerStream: ss, call: c})
+}
+
+// grpcCall tracks a gRPC call for its request log
+type grpcCall struct {
+	h        *grpcHandler
+	ctx      context.Context
+	r        *http.Request
+	ex       *Exclusion
+	l        *requestLogger
+	span     trace.Span
+	method   string
+	begin    time.Time
+	sent     atomic.Int64
+	received atomic.Int64
+}
+
+// start injects a Logger into the context of a call to method, and starts its span
+func (h *grpcHandler) start(ctx context.Context, method string) *grpcCall {
+	c := &grpcCall{h: h, method: method, begin: time.Now()}
+	md, _ := metadata.FromIncomingContext(ctx)
+	header := metadataHeader(md)
+	remote := h.remoteSpanContext(header)
+
+	if h.tracerProvider != nil {
+		ctx = contextWithRemoteParent(ctx, remote)
+		service, name := splitMethod(method)
+		ctx, c.span = h.tracerProvider.Tracer(tracerName).Start(ctx, strings.TrimPrefix(method, "/"),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithTimestamp(c.begin),
+			trace.WithAttributes(semconv.RPCSystemGRPC, semconv.RPCService(service), semconv.RPCMethod(name)),
+		)
+	}
+
+	var traceID string
+	if c.span == nil && remote.TraceID().IsValid() {
+		traceID = remote.TraceID().String()
+	} else {
+		traceID = traceIDFromContext(ctx)
+	}
+
+	c.l = newRequestLogger(h.sink, nil, traceID)
+	c.l.operation = method
+	c.l.route = method
+	c.l.level = h.level
+	c.l.source = !h.noSource
+	c.l.stack = h.errorStack
+	if h.debugEnabled(header) {
+		c.l.level = nil
+		c.l.debug = true
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		c.l.remoteIP = p.Addr.String()
+		if addr, ok := parseAddr(c.l.remoteIP); ok {
+			c.l.remoteIP = addr.String()
+		}
+	}
+	c.ctx = newContext(ctx, c.l)
+	c.r = (&http.Request{
+		Method:     http.MethodPost,
+		URL:        &url.URL{Path: method},
+		Proto:      "HTTP/2.0",
+		ProtoMajor: 2,
+		Header:     header,
+		Body:       http.NoBody,
+		RemoteAddr: c.l.remoteIP,
+	}).WithContext(c.ctx)
+	c.ex = h.exclusion(c.r)
+	// The sampling decision is made at the end of the call, so logs are held until then
+	c.l.buffer = h.buffer || h.sampler != nil || (c.ex != nil && c.ex.SuppressChildren)
+
+	return c
+}
+
+// end writes the logs of the call and its request log, and ends its span. A recovered panic p is logged,
+// and returned as an Internal error unless Repanic is set.
+func (c *grpcCall) end(p interface{}, err error) error {
+	if p != nil {
+		logPanic(c.ctx, c.l, p)
+		err = status.Error(codes.Internal, "internal error")
+	}
+
+	code := status.Code(err)
+	latency := time.Since(c.begin)
+	failed := grpcStatus(code) >= http.StatusInternalServerError || p != nil
+	if fields, ok := c.h.finish(c.r, c.l, grpcStatus(code), latency, failed, c.ex); ok {
+		c.writeParent(code, latency, err, fields)
+	}
+
+	if c.span != nil {
+		c.span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+		if failed {
+			c.span.SetStatus(otelcodes.Error, code.String())
+		}
+		c.span.End()
+	}
+
+	if p != nil && c.h.repanic {
+		panic(p)
+	}
+
+	return err
+}
+
+// writeParent writes the request log of the call
+func (c *grpcCall) writeParent(code codes.Code, latency time.Duration, err error, fields []Field) {
+	l := c.l
+	l.mu.Lock()
+	logCount := l.logCount
+	maxSeverity := l.maxSeverity
+	requestFields := l.requestFields
+	l.mu.Unlock()
+
+	if !c.h.logAll && logCount == 0 {
+		return
+	}
+
+	if severity := c.h.severityPolicy.Severity(grpcStatus(code), latency); maxSeverity < severity {
+		maxSeverity = severity
+	}
+
+	fields = append(fields, Field{Key: "grpc", Value: []Field{
+		{Key: "method", Value: c.method},
+		{Key: "code", Value: code.String()},
+		{Key: "peer", Value: l.remoteIP},
+		{Key: "latency", Value: latency.String()},
+		{Key: "messages_received", Value: c.received.Load()},
+		{Key: "messages_sent", Value: c.sent.Load()},
+	}})
+	if err != nil {
+		fields = append(fields, Field{Key: "error", Value: status.Convert(err).Message()})
+	}
+	if l.debug {
+		fields = append(fields, Field{Key: "debug", Value: true})
+	}
+	fields = append(fields, requestFields...)
+
+	message := callMessage(c.method, code.String(), latency)
+	if c.h.parentMessage != nil {
+		message = c.h.parentMessage(&HTTPRequest{
+			Request:  c.r,
+			Status:   grpcStatus(code),
+			Latency:  latency,
+			RemoteIP: l.remoteIP,
+			Route:    c.method,
+			User:     l.user(),
+		})
+	}
+
+	sc := trace.SpanFromContext(c.ctx).SpanContext()
+
+	l.sink.WriteParent(&Record{
+		Time:         c.begin,
+		Severity:     maxSeverity,
+		Message:      message,
+		Fields:       fields,
+		TraceID:      l.traceID,
+		SpanID:       sc.SpanID().String(),
+		TraceSampled: sc.IsSampled(),
+		Operation:    c.method,
+	})
+}
+
+// callMessage returns the default message of the request log of a call, such as
+// "/grpc.health.v1.Health/Check OK 2ms"
+func callMessage(method, code string, latency time.Duration) string {
+	return fmt.Sprintf("%s %s %s", method, code, roundLatency(latency))
+}
+
+// grpcCode returns the code named s, or codes.Unknown
+func grpcCode(s string) codes.Code {
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		if c.String() == s {
+			return c
+		}
+	}
+
+	return codes.Unknown
+}
+
+// loggedStream counts the messages of a stream, and carries the context with the Logger
+type loggedStream struct {
+	grpc.ServerStream
+	call *grpcCall
+}
+
+// Context returns the context of the stream, with the Logger
+func (s *loggedStream) Context() context.Context {
+	return s.call.ctx
+}
+
+// SendMsg sends a message, and counts it if it was sent
+func (s *loggedStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.call.sent.Add(1)
+	}
+
+	return err
+}
+
+// RecvMsg receives a message, and counts it if it was received
+func (s *loggedStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.call.received.Add(1)
+	}
+
+	return err
+}
+
+// metadataHeader converts gRPC metadata to an http.Header, so it can be read by the Propagators
+func metadataHeader(md metadata.MD) http.Header {
+	header := make(http.Header, len(md))
+	for k, v := range md {
+		header[http.CanonicalHeaderKey(k)] = v
+	}
+
+	return header
+}
+
+// splitMethod splits a full method name such as "/package.Service/Method" into the service and method
+func splitMethod(fullMethod string) (string, string) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return "", service
+	}
+
+	return service, method
+}
+
+// grpcStatus returns the HTTP status equivalent to a gRPC status code, so the SeverityPolicy
+// applies to gRPC calls the same way it applies to HTTP requests
+func grpcStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499 // Client Closed Request
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		// Unknown, Internal, DataLoss
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,350 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging"
+	"github.com/go-test/deep"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestSinkExporter_UnaryServerInterceptor(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		service      string
+		wantCode     codes.Code
+		wantSeverity logging.Severity
+		wantSent     int64
+		wantChildren int
+	}{
+		{name: "OK", service: "", wantCode: codes.OK, wantSeverity: logging.Info, wantSent: 1, wantChildren: 1},
+		{name: "NotFound", service: "missing", wantCode: codes.NotFound, wantSeverity: logging.Error, wantChildren: 1},
+		{name: "Internal", service: "broken", wantCode: codes.Internal, wantSeverity: logging.Error, wantChildren: 1},
+		{name: "panic", service: "panic", wantCode: codes.Internal, wantSeverity: logging.Error, wantChildren: 2},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &captureSink{}
+			client := newTestHealthClient(t, grpc.UnaryInterceptor(NewSinkExporter(s).UnaryServerInterceptor()))
+
+			ctx := metadata.AppendToOutgoingContext(context.Background(), "traceparent", "00-105445aa7843bc8bf206b12000100000-0000000000000001-01")
+			_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: tt.service})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("Check() code = %v, want %v", code, tt.wantCode)
+			}
+
+			if len(s.parents) != 1 {
+				t.Fatalf("parents = %d, want 1", len(s.parents))
+			}
+			if len(s.children) != tt.wantChildren {
+				t.Errorf("children = %d, want %d", len(s.children), tt.wantChildren)
+			}
+
+			p := s.parents[0]
+			if p.Severity != tt.wantSeverity {
+				t.Errorf("parent Severity = %v, want %v", p.Severity, tt.wantSeverity)
+			}
+			if p.Operation != "/grpc.health.v1.Health/Check" {
+				t.Errorf("parent Operation = %v, want %v", p.Operation, "/grpc.health.v1.Health/Check")
+			}
+			for _, rec := range append(s.children, p) {
+				if rec.TraceID != "105445aa7843bc8bf206b12000100000" {
+					t.Errorf("TraceID = %v, want %v", rec.TraceID, "105445aa7843bc8bf206b12000100000")
+				}
+			}
+
+			want := []Field{
+				{Key: "method", Value: "/grpc.health.v1.Health/Check"},
+				{Key: "code", Value: tt.wantCode.String()},
+				{Key: "peer", Value: "bufconn"},
+				{Key: "messages_received", Value: int64(1)},
+				{Key: "messages_sent", Value: tt.wantSent},
+			}
+			if diff := deep.Equal(withoutLatency(fieldGroup(p.Fields, "grpc")), want); diff != nil {
+				t.Errorf("parent grpc fields = %v", diff)
+			}
+		})
+	}
+}
+
+func TestSinkExporter_UnaryServerInterceptor_options(t *testing.T) {
+	t.Parallel()
+
+	const check = "/grpc.health.v1.Health/Check"
+	secret := []byte("secret")
+
+	tests := []struct {
+		name         string
+		opts         []Option
+		service      string
+		debug        bool
+		wantParents  int
+		wantChildren int
+		wantMessage  string
+	}{
+		{
+			name: "excluded",
+			opts: []Option{WithExclude(Exclusion{Match: ExactPath("POST " + check), SuppressChildren: true})},
+		},
+		{
+			name:         "excluded keeps failures",
+			opts:         []Option{WithExclude(Exclusion{Match: ExactPath(check), SuppressChildren: true, KeepFailures: true})},
+			service:      "broken",
+			wantParents:  1,
+			wantChildren: 1,
+			wantMessage:  check + " Internal",
+		},
+		{
+			name: "sampled out by method",
+			opts: []Option{WithSampler(RouteRatioSampler(map[string]float64{check: 0}, 1))},
+		},
+		{
+			name:         "debug token is not sampled out",
+			opts:         []Option{WithSampler(RatioSampler(0)), WithDebugSecret(secret)},
+			debug:        true,
+			wantParents:  1,
+			wantChildren: 1,
+			wantMessage:  check + " OK",
+		},
+		{
+			name: "parent message",
+			opts: []Option{WithParentMessage(func(req *HTTPRequest) string {
+				return fmt.Sprintf("%s %s %d", req.Request.Method, req.Route, req.Status)
+			})},
+			service:      "missing",
+			wantParents:  1,
+			wantChildren: 1,
+			wantMessage:  "POST " + check + " 404",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &captureSink{}
+			client := newTestHealthClient(t, grpc.UnaryInterceptor(NewSinkExporter(s).Options(tt.opts...).UnaryServerInterceptor()))
+
+			ctx := context.Background()
+			if tt.debug {
+				ctx = metadata.AppendToOutgoingContext(ctx, DebugHeader, NewDebugToken(secret, time.Now().Add(time.Minute)))
+			}
+			_, _ = client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: tt.service})
+
+			if len(s.parents) != tt.wantParents || len(s.children) != tt.wantChildren {
+				t.Fatalf("parents = %d, children = %d, want %d and %d", len(s.parents), len(s.children), tt.wantParents, tt.wantChildren)
+			}
+			if tt.wantParents == 0 {
+				return
+			}
+			if msg, _ := s.parents[0].Message.(string); !strings.HasPrefix(msg, tt.wantMessage) {
+				t.Errorf("parent Message = %v, want prefix %v", msg, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestConsoleExporter_UnaryServerInterceptor(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	client := newTestHealthClient(t, grpc.UnaryInterceptor(
+		NewConsoleExporter().NoColor(true).Options(WithSourceLocation(false)).UnaryServerInterceptor(),
+	))
+	if _, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	want := `(?m)^.{20}200  : /grpc\.health\.v1\.Health/Check OK \S+ grpc\.peer=bufconn grpc\.messages_received=1 grpc\.messages_sent=1$`
+	if s := buf.String(); !regexp.MustCompile(want).MatchString(s) {
+		t.Errorf("ConsoleExporter.UnaryServerInterceptor() output = %q, want match %q", s, want)
+	}
+}
+
+func TestSinkExporter_StreamServerInterceptor(t *testing.T) {
+	t.Parallel()
+
+	sr := tracetest.NewSpanRecorder()
+	s := &captureSink{}
+	client := newTestHealthClient(t, grpc.StreamInterceptor(
+		NewSinkExporter(s).Options(WithServerSpan(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))).StreamServerInterceptor(),
+	))
+
+	stream, err := client.Watch(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "broken"})
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	var received int
+	for {
+		if _, err = stream.Recv(); err != nil {
+			break
+		}
+		received++
+	}
+	if received != 3 || status.Code(err) != codes.Unavailable {
+		t.Fatalf("Recv() messages = %d, code = %v, want 3 and %v", received, status.Code(err), codes.Unavailable)
+	}
+
+	if len(s.parents) != 1 || len(s.children) != 1 {
+		t.Fatalf("parents = %d, children = %d, want 1 and 1", len(s.parents), len(s.children))
+	}
+	p := s.parents[0]
+	if p.Severity != logging.Error {
+		t.Errorf("parent Severity = %v, want %v", p.Severity, logging.Error)
+	}
+	want := []Field{
+		{Key: "method", Value: "/grpc.health.v1.Health/Watch"},
+		{Key: "code", Value: "Unavailable"},
+		{Key: "peer", Value: "bufconn"},
+		{Key: "messages_received", Value: int64(1)},
+		{Key: "messages_sent", Value: int64(3)},
+	}
+	if diff := deep.Equal(withoutLatency(fieldGroup(p.Fields, "grpc")), want); diff != nil {
+		t.Errorf("parent grpc fields = %v", diff)
+	}
+
+	spans := sr.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("spans = %d, want 1", len(spans))
+	}
+	if spans[0].Name() != "grpc.health.v1.Health/Watch" || spans[0].Status().Code != otelcodes.Error {
+		t.Errorf("span = %v %v, want grpc.health.v1.Health/Watch with an error status", spans[0].Name(), spans[0].Status())
+	}
+	if tid := spans[0].SpanContext().TraceID().String(); p.TraceID != tid || s.children[0].TraceID != tid {
+		t.Errorf("TraceID = %v, want %v", p.TraceID, tid)
+	}
+}
+
+func Test_grpcStatus(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		code codes.Code
+		want int
+	}{
+		{code: codes.OK, want: http.StatusOK},
+		{code: codes.Canceled, want: 499},
+		{code: codes.InvalidArgument, want: http.StatusBadRequest},
+		{code: codes.NotFound, want: http.StatusNotFound},
+		{code: codes.Unauthenticated, want: http.StatusUnauthorized},
+		{code: codes.Unavailable, want: http.StatusServiceUnavailable},
+		{code: codes.Unknown, want: http.StatusInternalServerError},
+		{code: codes.DataLoss, want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.code.String(), func(t *testing.T) {
+			t.Parallel()
+			if got := grpcStatus(tt.code); got != tt.want {
+				t.Errorf("grpcStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// testHealthServer logs during each call, and fails depending on the service requested
+type testHealthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+}
+
+func (testHealthServer) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	Ctx(ctx).Info("checking")
+	switch req.GetService() {
+	case "missing":
+		return nil, status.Error(codes.NotFound, "unknown service")
+	case "broken":
+		return nil, status.Error(codes.Internal, "broken")
+	case "panic":
+		panic("boom")
+	}
+
+	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+}
+
+func (testHealthServer) Watch(req *grpc_health_v1.HealthCheckRequest, stream grpc_health_v1.Health_WatchServer) error {
+	Ctx(stream.Context()).Error("watching")
+	for i := 0; i < 3; i++ {
+		if err := stream.Send(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_NOT_SERVING}); err != nil {
+			return err
+		}
+	}
+
+	return status.Error(codes.Unavailable, req.GetService())
+}
+
+// newTestHealthClient starts an in-process gRPC server with testHealthServer, and returns a client for it
+func newTestHealthClient(t *testing.T, opts ...grpc.ServerOption) grpc_health_v1.HealthClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(opts...)
+	grpc_health_v1.RegisterHealthServer(srv, testHealthServer{})
+	go func() {
+		if err := srv.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			t.Errorf("Serve() error = %v", err)
+		}
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("DialContext() error = %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return grpc_health_v1.NewHealthClient(conn)
+}
+
+// fieldGroup returns the Fields of the group key in fields
+func fieldGroup(fields []Field, key string) []Field {
+	for _, f := range fields {
+		if f.Key == key {
+			g, _ := f.Value.([]Field)
+
+			return g
+		}
+	}
+
+	return nil
+}
+
+// withoutLatency returns fields without the latency, which varies between runs
+func withoutLatency(fields []Field) []Field {
+	var out []Field
+	for _, f := range fields {
+		if f.Key != "latency" {
+			out = append(out, f)
+		}
+	}
+
+	return out
+}
@@ -1,12 +1,36 @@
 package logger
 
 import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/netip"
+	"runtime/debug"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"cloud.google.com/go/logging"
 	"github.com/go-playground/errors/v5"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.20.0"
+	"go.opentelemetry.io/otel/semconv/v1.20.0/httpconv"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracerName is the instrumentation name of the server spans started by the middleware
+const tracerName = "github.com/jtwatson/logger"
+
+// userField is the request field that identifies the user of a request
+const userField = "user"
+
 // NewRequestLogger returns a middleware that logs the request and injects a Logger into
 // the context. This Logger can be used during the life of the request, and all logs
 // generated will be correlated to the request log.
@@ -21,6 +45,560 @@ type Exporter interface {
 	Middleware() func(http.Handler) http.Handler
 }
 
+// config holds the settings shared by all Exporters
+type config struct {
+	logAll         bool
+	repanic        bool
+	propagators    []Propagator
+	tracerProvider trace.TracerProvider
+	level          *LevelVar
+	debugSecret    []byte
+	buffer         bool
+	slowRequest    time.Duration
+	sampler        Sampler
+	exclusions     []Exclusion
+	trustedProxies []netip.Prefix
+	trustedHops    int
+	severityPolicy *SeverityPolicy
+	parentMessage  MessageFunc
+	routeResolver  RouteResolver
+	noSource       bool
+	errorStack     bool
+}
+
+// requestHandler is the middleware shared by all Exporters. It writes
+// the request log and all logs generated during the request to a Sink.
+type requestHandler struct {
+	next http.Handler
+	sink Sink
+	config
+}
+
+func (h *requestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	begin := time.Now()
+	r, span := h.startSpan(r, begin)
+	traceID := h.traceIDFromRequest(r)
+	if span != nil && span.SpanContext().IsValid() {
+		traceID = span.SpanContext().TraceID().String()
+	}
+	l := newRequestLogger(h.sink, r, traceID)
+	l.level = h.level
+	l.source = !h.noSource
+	l.stack = h.errorStack
+	l.remoteIP = h.clientIP(r)
+	ex := h.exclusion(r)
+	// The sampling decision is made at the end of the request, so logs are held until then
+	l.buffer = h.buffer || h.sampler != nil || (ex != nil && ex.SuppressChildren)
+	if h.debugEnabled(r.Header) {
+		l.level = nil
+		l.debug = true
+	}
+	r = r.WithContext(newContext(r.Context(), l))
+	if r.Body != nil && r.Body != http.NoBody {
+		r.Body = &countingBody{ReadCloser: r.Body}
+	}
+	w, sw := wrapWriter(w)
+
+	defer func() {
+		p := recover()
+		if p != nil && p != http.ErrAbortHandler {
+			logPanic(r.Context(), l, p)
+			if !h.repanic && sw.status == 0 && !sw.hijacked.Load() {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}
+
+		status := sw.Status()
+		if p != nil {
+			status = http.StatusInternalServerError
+		}
+
+		l.mu.Lock()
+		l.route = l.setRoute
+		l.mu.Unlock()
+		if l.route == "" {
+			l.route = h.route(r)
+		}
+		h.complete(r, l, sw, begin, status, p != nil, ex)
+		endSpan(span, r, status, l.route, p)
+
+		if p != nil && (h.repanic || p == http.ErrAbortHandler) {
+			panic(p)
+		}
+	}()
+
+	h.next.ServeHTTP(w, r)
+}
+
+// startSpan starts a server span for the request if a TracerProvider is configured. The span is
+// a child of the trace propagated in the request headers, or else of the span in the request context.
+func (h *requestHandler) startSpan(r *http.Request, begin time.Time) (*http.Request, trace.Span) {
+	if h.tracerProvider == nil {
+		return r, nil
+	}
+
+	ctx := contextWithRemoteParent(r.Context(), h.remoteSpanContext(r.Header))
+	ctx, span := h.tracerProvider.Tracer(tracerName).Start(ctx, r.Method+" "+r.URL.Path,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithTimestamp(begin),
+		trace.WithAttributes(httpconv.ServerRequest("", r)...),
+	)
+
+	return r.WithContext(ctx), span
+}
+
+// endSpan records the response status on span and ends it. If the route is known, the span is
+// renamed after it.
+func endSpan(span trace.Span, r *http.Request, status int, route string, p interface{}) {
+	if span == nil {
+		return
+	}
+
+	if route != "" {
+		span.SetName(r.Method + " " + route)
+		span.SetAttributes(semconv.HTTPRoute(route))
+	}
+	span.SetAttributes(semconv.HTTPStatusCode(status))
+	span.SetStatus(httpconv.ServerStatus(status))
+	if p != nil {
+		span.SetStatus(codes.Error, fmt.Sprintf("panic: %v", p))
+	}
+
+	span.End()
+}
+
+// logPanic writes a child log with the recovered panic value and the stack trace
+func logPanic(ctx context.Context, l *requestLogger, p interface{}) {
+	rec := l.record(ctx, logging.Error, fmt.Sprintf("panic: %v", p), nil)
+	rec.Stack = string(debug.Stack())
+	l.write(rec)
+}
+
+// complete writes the logs held by l and the request log, unless the request is excluded by ex or sampled out
+func (h *requestHandler) complete(r *http.Request, l *requestLogger, sw *statusWriter, begin time.Time, status int, panicked bool, ex *Exclusion) {
+	failed := status >= http.StatusInternalServerError || panicked
+	if fields, ok := h.finish(r, l, status, time.Since(begin), failed, ex); ok {
+		h.writeParent(r, l, sw, begin, status, fields)
+	}
+}
+
+// finish writes the logs held by l, and returns the fields for the request log. It reports false if
+// the request log is not written, because the request is excluded by ex or sampled out.
+func (c config) finish(r *http.Request, l *requestLogger, status int, latency time.Duration, failed bool, ex *Exclusion) ([]Field, bool) {
+	keep := !c.buffer || failed || l.debug || (c.slowRequest > 0 && latency >= c.slowRequest)
+
+	if ex != nil && !(ex.KeepFailures && failed) {
+		if ex.SuppressChildren {
+			l.discard()
+		} else {
+			l.flush(keep)
+		}
+
+		return nil, false
+	}
+
+	sampled, fields := c.sample(r, l, status, latency)
+	if !sampled {
+		l.discard()
+
+		return nil, false
+	}
+
+	return append(fields, l.flush(keep)...), true
+}
+
+// sample consults the Sampler, and returns the fields recording its decision for the request log.
+// A request with a valid debug token is always logged.
+func (c config) sample(r *http.Request, l *requestLogger, status int, latency time.Duration) (bool, []Field) {
+	if c.sampler == nil {
+		return true, nil
+	}
+	if l.debug {
+		return true, samplingFields(SamplingResult{Sample: true, Rate: 1, Reason: "debug"})
+	}
+
+	sc := trace.SpanFromContext(r.Context()).SpanContext()
+	if !sc.IsValid() {
+		sc = c.remoteSpanContext(r.Header)
+	}
+
+	l.mu.Lock()
+	maxSeverity := l.maxSeverity
+	l.mu.Unlock()
+
+	res := c.sampler.Sample(&SampleInfo{
+		Request:      r,
+		Route:        l.route,
+		Status:       status,
+		Latency:      latency,
+		MaxSeverity:  maxSeverity,
+		TraceID:      l.traceID,
+		TraceSampled: sc.IsSampled(),
+	})
+	if !res.Sample {
+		return false, nil
+	}
+
+	return true, samplingFields(res)
+}
+
+// samplingFields returns the fields recording the sampling decision res for the request log
+func samplingFields(res SamplingResult) []Field {
+	return []Field{{Key: "sampling", Value: []Field{
+		{Key: "reason", Value: res.Reason},
+		{Key: "rate", Value: res.Rate},
+	}}}
+}
+
+func (h *requestHandler) writeParent(r *http.Request, l *requestLogger, sw *statusWriter, begin time.Time, status int, fields []Field) {
+	l.mu.Lock()
+	logCount := l.logCount
+	maxSeverity := l.maxSeverity
+	l.mu.Unlock()
+
+	if !h.logAll && logCount == 0 {
+		return
+	}
+
+	latency := time.Since(begin)
+
+	// the response should also set the minimum maxSeverity
+	if severity := h.severityPolicy.Severity(status, latency); maxSeverity < severity {
+		maxSeverity = severity
+	}
+
+	if sw.hijacked.Load() {
+		fields = append(fields, Field{Key: "hijacked", Value: true})
+	}
+	if l.debug {
+		fields = append(fields, Field{Key: "debug", Value: true})
+	}
+
+	size := requestSize(r.Header.Get("Content-Length"))
+	if b, ok := r.Body.(*countingBody); ok {
+		if b.read.Load() {
+			size = b.n.Load()
+		}
+		fields = append(fields, Field{Key: "request_body_consumed", Value: b.eof.Load()})
+	}
+
+	l.mu.Lock()
+	fields = append(fields, l.requestFields...)
+	l.mu.Unlock()
+
+	if l.route != "" {
+		fields = append(fields, Field{Key: "route", Value: l.route})
+	}
+
+	req := &HTTPRequest{
+		Request:      r,
+		Route:        l.route,
+		RequestSize:  size,
+		Latency:      latency,
+		Status:       status,
+		ResponseSize: sw.Length(),
+		RemoteIP:     l.remoteIP,
+		User:         l.user(),
+	}
+
+	message := h.parentMessage
+	if message == nil {
+		message = defaultParentMessage
+	}
+
+	sc := trace.SpanFromContext(r.Context()).SpanContext()
+
+	h.sink.WriteParent(&Record{
+		Time:         begin,
+		Severity:     maxSeverity,
+		Message:      message(req),
+		Fields:       fields,
+		TraceID:      l.traceID,
+		SpanID:       sc.SpanID().String(),
+		TraceSampled: sc.IsSampled(),
+		Request:      req,
+	})
+}
+
+// MessageFunc returns the message of the request log. For a gRPC call, the Request is a POST request
+// to the full method, the Route is the full method, and the Status is the equivalent HTTP status.
+type MessageFunc func(req *HTTPRequest) string
+
+// defaultParentMessage returns a message such as "GET /orders/{id} 200 12ms", with the path
+// in place of the route if it is unknown
+func defaultParentMessage(req *HTTPRequest) string {
+	route := req.Route
+	if route == "" {
+		route = req.Request.URL.Path
+	}
+
+	return fmt.Sprintf("%s %s %d %s", req.Request.Method, route, req.Status, roundLatency(req.Latency))
+}
+
+// roundLatency rounds d to milliseconds, or to microseconds if it is under a millisecond
+func roundLatency(d time.Duration) time.Duration {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond)
+	}
+
+	return d.Round(time.Millisecond)
+}
+
+// RouteResolver returns the route template matched by a request, such as "/users/{id}", or an empty
+// string if it is unknown. It is called once the handler has returned, with the request passed to the
+// middleware, so it does not see changes made by handlers in the chain with http.Request.WithContext.
+// Use Logger.SetRoute from inside the chain in that case. A route set with SetRoute takes precedence.
+type RouteResolver func(r *http.Request) string
+
+// route returns the route template matched by the request, from the RouteResolver or else the
+// pattern set by http.ServeMux on the request passed to the middleware (Go 1.23 or later). The method
+// and a space are removed from the start of the pattern.
+func (c config) route(r *http.Request) string {
+	if c.routeResolver != nil {
+		return c.routeResolver(r)
+	}
+
+	pattern := servePattern(r)
+	if _, route, ok := strings.Cut(pattern, " "); ok {
+		return route
+	}
+
+	return pattern
+}
+
+// traceIDFromRequest returns the hex encoded trace ID for the request. The trace propagated in the
+// request headers is used first, then the span in the request context. If neither is found, a new
+// trace ID is generated.
+func (c config) traceIDFromRequest(r *http.Request) string {
+	if sc := c.remoteSpanContext(r.Header); sc.TraceID().IsValid() {
+		return sc.TraceID().String()
+	}
+
+	return traceIDFromContext(r.Context())
+}
+
+// traceIDFromContext returns the trace ID of the span in ctx, or a random trace ID if there is none
+func traceIDFromContext(ctx context.Context) string {
+	sc := trace.SpanFromContext(ctx).SpanContext()
+	if sc.IsValid() {
+		return sc.TraceID().String()
+	}
+
+	var tid trace.TraceID
+	_, _ = rand.Read(tid[:])
+
+	return tid.String()
+}
+
+// contextWithRemoteParent returns ctx with sc as the remote parent of the spans started from it. A trace
+// propagated without a span ID, such as an X-Cloud-Trace-Context header with no span, is given a random
+// span ID, as spans are only parented on a valid span context.
+func contextWithRemoteParent(ctx context.Context, sc trace.SpanContext) context.Context {
+	if !sc.TraceID().IsValid() {
+		return ctx
+	}
+	if !sc.SpanID().IsValid() {
+		var sid trace.SpanID
+		_, _ = rand.Read(sid[:])
+		sc = sc.WithSpanID(sid)
+	}
+
+	return trace.ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// remoteSpanContext returns the trace context propagated in the headers. The Propagators
+// are tried in order, and the first to find a trace ID wins.
+func (c config) remoteSpanContext(header http.Header) trace.SpanContext {
+	propagators := c.propagators
+	if propagators == nil {
+		propagators = defaultPropagators()
+	}
+
+	for _, p := range propagators {
+		if sc := p.Extract(header); sc.TraceID().IsValid() {
+			return sc
+		}
+	}
+
+	return trace.SpanContext{}
+}
+
+// requestLogger is the ctxLogger injected into the request context. It writes
+// logs to a Sink, and tracks the details needed for the request log.
+type requestLogger struct {
+	sink     Sink
+	r        *http.Request
+	traceID  string
+	level    *LevelVar
+	debug    bool
+	source   bool
+	stack    bool
+	remoteIP string
+	route    string
+	// setRoute is the route set with SetRoute from inside the handler chain
+	setRoute string
+	// operation is the name of the operation started with StartOperation, or the gRPC method, for logs outside an HTTP request
+	operation string
+	buffer    bool
+	buffered  []*Record
+	// requestFields are added to the request log
+	requestFields []Field
+	mu            sync.Mutex
+	maxSeverity   logging.Severity
+	logCount      int
+}
+
+func newRequestLogger(sink Sink, r *http.Request, traceID string) *requestLogger {
+	return &requestLogger{
+		sink:    sink,
+		r:       r,
+		traceID: traceID,
+	}
+}
+
+// Log writes a log to the Sink
+func (l *requestLogger) Log(ctx context.Context, severity logging.Severity, v interface{}, fields []Field, pc uintptr) {
+	rec := l.record(ctx, severity, v, fields)
+	if l.source {
+		rec.Source = source(pc)
+	}
+	if l.stack && severity >= logging.Error {
+		rec.Stack = callerStack(pc)
+	}
+	l.write(rec)
+}
+
+// SetRequestField adds a field to the request log, replacing any field with the same key
+func (l *requestLogger) SetRequestField(key string, value interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i := range l.requestFields {
+		if l.requestFields[i].Key == key {
+			l.requestFields[i].Value = value
+
+			return
+		}
+	}
+	l.requestFields = append(l.requestFields, Field{Key: key, Value: value})
+}
+
+// SetRoute sets the route template matched by the request, in place of the one found by the middleware
+func (l *requestLogger) SetRoute(route string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.setRoute = route
+}
+
+// user returns the value of the "user" request field, or an empty string if it is not set
+func (l *requestLogger) user() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, f := range l.requestFields {
+		if f.Key == userField {
+			return fmt.Sprint(f.Value)
+		}
+	}
+
+	return ""
+}
+
+// write sends rec to the Sink, and tracks it for the request log. Logs below the level are dropped.
+func (l *requestLogger) write(rec *Record) {
+	if !l.level.enabled(rec.Severity) {
+		return
+	}
+
+	l.mu.Lock()
+	if l.maxSeverity < rec.Severity {
+		l.maxSeverity = rec.Severity
+	}
+	l.logCount++
+	if l.buffer {
+		l.buffered = append(l.buffered, rec)
+		l.mu.Unlock()
+
+		return
+	}
+	l.mu.Unlock()
+
+	l.sink.WriteChild(rec)
+}
+
+// discard ends buffering, and drops the buffered logs
+func (l *requestLogger) discard() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.buffer = false
+	l.buffered = nil
+}
+
+// flush ends buffering. The buffered logs are written to the Sink if keep is true, or if one of
+// them is an Error or above. Otherwise they are dropped, and a field with the number dropped of
+// each severity is returned for the request log.
+func (l *requestLogger) flush(keep bool) []Field {
+	l.mu.Lock()
+	buffered := l.buffered
+	keep = keep || l.maxSeverity >= logging.Error
+	l.buffer = false
+	l.buffered = nil
+	l.mu.Unlock()
+
+	if len(buffered) == 0 {
+		return nil
+	}
+
+	if keep {
+		for _, rec := range buffered {
+			l.sink.WriteChild(rec)
+		}
+
+		return nil
+	}
+
+	counts := make(map[logging.Severity]int)
+	for _, rec := range buffered {
+		counts[rec.Severity]++
+	}
+	severities := make([]logging.Severity, 0, len(counts))
+	for s := range counts {
+		severities = append(severities, s)
+	}
+	sort.Slice(severities, func(i, j int) bool { return severities[i] < severities[j] })
+
+	dropped := make([]Field, 0, len(severities))
+	for _, s := range severities {
+		dropped = append(dropped, Field{Key: s.String(), Value: counts[s]})
+	}
+
+	return []Field{{Key: "dropped_logs", Value: dropped}}
+}
+
+// record returns a child Record for the log
+func (l *requestLogger) record(ctx context.Context, severity logging.Severity, v interface{}, fields []Field) *Record {
+	sc := trace.SpanFromContext(ctx).SpanContext()
+	rec := &Record{
+		Time:         time.Now(),
+		Severity:     severity,
+		Message:      v,
+		Fields:       fields,
+		TraceID:      l.traceID,
+		SpanID:       sc.SpanID().String(),
+		TraceSampled: sc.IsSampled(),
+		Operation:    l.operation,
+	}
+	if l.r != nil {
+		rec.Request = &HTTPRequest{Request: l.r, RemoteIP: l.remoteIP, User: l.user()}
+	}
+
+	return rec
+}
+
+// requestSize returns the request size from the Content-Length header, used when the handler
+// does not read the request body
 func requestSize(length string) int64 {
 	l, err := strconv.Atoi(length)
 	if err != nil {
@@ -30,13 +608,96 @@ func requestSize(length string) int64 {
 	return int64(l)
 }
 
+// countingBody counts the bytes of the request body read by the handler. Errors are returned
+// unchanged, so the handler sees the same errors as without the middleware.
+type countingBody struct {
+	io.ReadCloser
+	n    atomic.Int64
+	read atomic.Bool
+	eof  atomic.Bool
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	b.read.Store(true)
+
+	n, err := b.ReadCloser.Read(p)
+	b.n.Add(int64(n))
+	if errors.Is(err, io.EOF) {
+		b.eof.Store(true)
+	}
+
+	return n, err
+}
+
+// wrapWriter wraps w in a statusWriter. The returned http.ResponseWriter implements
+// exactly the optional interfaces (http.Flusher, http.Hijacker and io.ReaderFrom) that w does.
+func wrapWriter(w http.ResponseWriter) (http.ResponseWriter, *statusWriter) {
+	sw := &statusWriter{ResponseWriter: w}
+
+	f, isF := w.(http.Flusher)
+	h, isH := w.(http.Hijacker)
+	r, isR := w.(io.ReaderFrom)
+
+	switch {
+	case isF && isH && isR:
+		return &struct {
+			*statusWriter
+			flusher
+			hijacker
+			readerFrom
+		}{sw, flusher{sw, f}, hijacker{sw, h}, readerFrom{sw, r}}, sw
+	case isF && isH:
+		return &struct {
+			*statusWriter
+			flusher
+			hijacker
+		}{sw, flusher{sw, f}, hijacker{sw, h}}, sw
+	case isF && isR:
+		return &struct {
+			*statusWriter
+			flusher
+			readerFrom
+		}{sw, flusher{sw, f}, readerFrom{sw, r}}, sw
+	case isH && isR:
+		return &struct {
+			*statusWriter
+			hijacker
+			readerFrom
+		}{sw, hijacker{sw, h}, readerFrom{sw, r}}, sw
+	case isF:
+		return &struct {
+			*statusWriter
+			flusher
+		}{sw, flusher{sw, f}}, sw
+	case isH:
+		return &struct {
+			*statusWriter
+			hijacker
+		}{sw, hijacker{sw, h}}, sw
+	case isR:
+		return &struct {
+			*statusWriter
+			readerFrom
+		}{sw, readerFrom{sw, r}}, sw
+	default:
+		return sw, sw
+	}
+}
+
 type statusWriter struct {
 	http.ResponseWriter
-	status int
-	length int64
+	status   int
+	length   int64
+	hijacked atomic.Bool
+	// hijackedLength is the number of bytes written to the connection after it was hijacked
+	hijackedLength atomic.Int64
 }
 
 func (w *statusWriter) Status() int {
+	if w.hijacked.Load() {
+		return http.StatusSwitchingProtocols
+	}
+
 	if w.status == 0 {
 		return http.StatusOK
 	}
@@ -44,6 +705,16 @@ func (w *statusWriter) Status() int {
 	return w.status
 }
 
+// Length returns the number of bytes written to the response
+func (w *statusWriter) Length() int64 {
+	return w.length + w.hijackedLength.Load()
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for use by http.ResponseController
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 func (w *statusWriter) WriteHeader(status int) {
 	w.status = status
 	w.ResponseWriter.WriteHeader(status)
@@ -62,3 +733,65 @@ func (w *statusWriter) Write(b []byte) (int, error) {
 
 	return n, nil
 }
+
+type flusher struct {
+	w *statusWriter
+	f http.Flusher
+}
+
+func (f flusher) Flush() {
+	if f.w.status == 0 {
+		f.w.status = 200
+	}
+
+	f.f.Flush()
+}
+
+type hijacker struct {
+	w *statusWriter
+	h http.Hijacker
+}
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := h.h.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	h.w.hijacked.Store(true)
+
+	if err := brw.Writer.Flush(); err != nil {
+		return nil, nil, err
+	}
+	cc := &countingConn{Conn: conn, n: &h.w.hijackedLength}
+
+	return cc, bufio.NewReadWriter(brw.Reader, bufio.NewWriterSize(cc, brw.Writer.Size())), nil
+}
+
+type readerFrom struct {
+	w *statusWriter
+	r io.ReaderFrom
+}
+
+func (r readerFrom) ReadFrom(src io.Reader) (int64, error) {
+	if r.w.status == 0 {
+		r.w.status = 200
+	}
+
+	n, err := r.r.ReadFrom(src)
+	r.w.length += n
+
+	return n, err
+}
+
+// countingConn counts the bytes written to a hijacked connection
+type countingConn struct {
+	net.Conn
+	n *atomic.Int64
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.n.Add(int64(n))
+
+	return n, err
+}
@@ -1,13 +1,25 @@
 package logger
 
 import (
+	"bufio"
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"cloud.google.com/go/logging"
 	"github.com/go-test/deep"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestNewRequestLogger(t *testing.T) {
@@ -29,12 +41,14 @@ func TestNewRequestLogger(t *testing.T) {
 			want: func(next http.Handler) http.Handler {
 				client := &logging.Client{}
 
-				return &gcpHandler{
-					next:         next,
-					parentLogger: client.Logger("request_parent_log"),
-					childLogger:  client.Logger("request_child_log"),
-					projectID:    "My first project",
-					logAll:       true,
+				return &requestHandler{
+					next: next,
+					sink: &gcpSink{
+						parentLogger: client.Logger("request_parent_log"),
+						childLogger:  client.Logger("request_child_log"),
+						projectID:    "My first project",
+					},
+					config: config{logAll: true},
 				}
 			},
 		},
@@ -51,6 +65,279 @@ func TestNewRequestLogger(t *testing.T) {
 	}
 }
 
+func Test_requestHandler_ServeHTTP(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		status int
+		logs   int
+		level  logging.Severity
+	}
+	type fields struct {
+		projectID string
+		logAll    bool
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		wantLevel logging.Severity
+	}{
+		{
+			name: "logAll=true",
+			fields: fields{
+				projectID: "my-big-project",
+				logAll:    true,
+			},
+			args: args{
+				status: http.StatusOK,
+				logs:   1,
+				level:  logging.Info,
+			},
+			wantLevel: logging.Info,
+		},
+		{
+			name: "logAll=true no logging",
+			fields: fields{
+				projectID: "my-big-project",
+				logAll:    true,
+			},
+			args: args{
+				status: http.StatusOK,
+			},
+			wantLevel: logging.Default,
+		},
+		{
+			name: "logAll=false no logging",
+			fields: fields{
+				projectID: "my-big-project",
+			},
+			args: args{
+				status: http.StatusOK,
+			},
+		},
+		{
+			name: "logAll=false with logging",
+			fields: fields{
+				projectID: "my-bigger-project",
+			},
+			args: args{
+				status: http.StatusOK,
+				logs:   1,
+				level:  logging.Warning,
+			},
+			wantLevel: logging.Warning,
+		},
+		{
+			name: "logAll=true no logging",
+			fields: fields{
+				projectID: "my-big-project",
+				logAll:    true,
+			},
+			args: args{
+				status: http.StatusInternalServerError,
+			},
+			wantLevel: logging.Error,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var handlerCalled bool
+			var traceID string
+			l := &captureLogger{}
+			handler := &requestHandler{
+				sink: &gcpSink{
+					parentLogger: l,
+					childLogger:  &captureLogger{},
+					projectID:    tt.fields.projectID,
+				},
+				config: config{logAll: tt.fields.logAll},
+				next: http.HandlerFunc(
+					func(w http.ResponseWriter, r *http.Request) {
+						for i := 0; i < tt.args.logs; i++ {
+							switch tt.args.level {
+							case logging.Info:
+								Req(r).Info("some log")
+							case logging.Warning:
+								Req(r).Warn("some log")
+							case logging.Error:
+								Req(r).Error("some log")
+							default:
+							}
+						}
+
+						l := Req(r)
+						if l, ok := l.lg.(*requestLogger); ok {
+							traceID = gcpTraceID(tt.fields.projectID, l.traceID)
+						} else {
+							t.Fatalf("Req() = %v, wanted: %T", l, &requestLogger{})
+						}
+
+						w.WriteHeader(tt.args.status)
+						handlerCalled = true
+					},
+				),
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			handler.ServeHTTP(w, r)
+
+			if !handlerCalled {
+				t.Errorf("Failed to call handler")
+			}
+			if tt.args.logs == 0 {
+				return
+			}
+			if l.e.Severity != tt.wantLevel {
+				t.Errorf("Severity = %v, want %v", l.e.Severity, tt.wantLevel)
+			}
+			if l.e.Trace != traceID {
+				t.Errorf("Trace = %v, want %v", l.e.Trace, traceID)
+			}
+			if pl, ok := l.e.Payload.(map[string]interface{}); ok {
+				if m, ok := pl["message"].(string); ok {
+					if want := fmt.Sprintf("GET / %d ", tt.args.status); !strings.HasPrefix(m, want) {
+						t.Errorf("Message = %v, want prefix %v", m, want)
+					}
+				} else {
+					t.Fatalf("Message = %T, want %T", pl["message"], "")
+				}
+			} else {
+				t.Fatalf("Payload = %T, want %T", l.e.Payload, map[string]interface{}{})
+			}
+			if l.e.HTTPRequest.Status != tt.args.status {
+				t.Errorf("Status = %v, want %v", l.e.HTTPRequest.Status, tt.args.status)
+			}
+		})
+	}
+}
+
+func Test_traceIDFromRequest(t *testing.T) {
+	type args struct {
+		mockReq func(traceStr string) (*http.Request, string)
+	}
+	tests := []struct {
+		name         string
+		propagators  []Propagator
+		args         args
+		wantTraceStr string
+	}{
+		// The order these are significant
+		{
+			// This test relies on the global tracing provider NOT being set
+			name: "no trace in request",
+			args: args{
+				mockReq: func(wantTraceStr string) (*http.Request, string) {
+					return &http.Request{URL: &url.URL{}}, wantTraceStr
+				},
+			},
+		},
+		{
+			// This test sets the global tracing provider (I don't think this can be un-done)
+			name: "with trace in request",
+			args: args{
+				mockReq: func(_ string) (r *http.Request, traceStr string) {
+					otel.SetTracerProvider(sdktrace.NewTracerProvider())
+					ctx, span := otel.Tracer("test/examples").Start(context.Background(), "test trace")
+
+					r = httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+					r = r.WithContext(ctx)
+
+					return r, span.SpanContext().TraceID().String()
+				},
+			},
+		},
+		{
+			// With the global tracing provider set, this test shows that
+			// trace Propagation is a higher priority then trace in request context
+			name: "with propagation span in headers",
+			args: args{
+				mockReq: func(wantTraceStr string) (r *http.Request, traceStr string) {
+					r = httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+					r.Header.Add("X-Cloud-Trace-Context", wantTraceStr+"/1;o=1")
+
+					return r, wantTraceStr
+				},
+			},
+			wantTraceStr: "105445aa7843bc8bf206b12000100000",
+		},
+		{
+			name: "with traceparent header",
+			args: args{
+				mockReq: func(wantTraceStr string) (r *http.Request, traceStr string) {
+					r = httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+					r.Header.Add("traceparent", "00-"+wantTraceStr+"-00f067aa0ba902b7-01")
+
+					return r, wantTraceStr
+				},
+			},
+			wantTraceStr: "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			name: "with b3 header",
+			args: args{
+				mockReq: func(wantTraceStr string) (r *http.Request, traceStr string) {
+					r = httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+					r.Header.Add("b3", wantTraceStr+"-00f067aa0ba902b7-1")
+
+					return r, wantTraceStr
+				},
+			},
+			wantTraceStr: "80f198ee56343ba864fe8b2a57d3eff7",
+		},
+		{
+			name: "default precedence",
+			args: args{
+				mockReq: func(wantTraceStr string) (r *http.Request, traceStr string) {
+					r = httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+					r.Header.Add("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+					r.Header.Add("X-Cloud-Trace-Context", wantTraceStr+"/1;o=1")
+
+					return r, wantTraceStr
+				},
+			},
+			wantTraceStr: "105445aa7843bc8bf206b12000100000",
+		},
+		{
+			name:        "configured precedence",
+			propagators: []Propagator{W3CPropagator{}, CloudTracePropagator{}},
+			args: args{
+				mockReq: func(wantTraceStr string) (r *http.Request, traceStr string) {
+					r = httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+					r.Header.Add("traceparent", "00-"+wantTraceStr+"-00f067aa0ba902b7-01")
+					r.Header.Add("X-Cloud-Trace-Context", "105445aa7843bc8bf206b12000100000/1;o=1")
+
+					return r, wantTraceStr
+				},
+			},
+			wantTraceStr: "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r, traceStr := tt.args.mockReq(tt.wantTraceStr)
+			c := config{propagators: tt.propagators}
+			got := c.traceIDFromRequest(r)
+			if traceStr == "" {
+				// A new trace ID is generated
+				if tid, err := trace.TraceIDFromHex(got); err != nil || !tid.IsValid() {
+					t.Errorf("traceIDFromRequest() = %v, want a valid trace ID", got)
+				}
+
+				return
+			}
+			if got != traceStr {
+				t.Errorf("traceIDFromRequest() = %v, want %v", got, traceStr)
+			}
+		})
+	}
+}
+
 func Test_requestSize(t *testing.T) {
 	t.Parallel()
 
@@ -250,3 +537,325 @@ type responseRecorder struct {
 func (rw *responseRecorder) Write(buf []byte) (int, error) {
 	return len(buf), rw.err
 }
+
+func Test_wrapWriter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		w           http.ResponseWriter
+		wantFlusher bool
+		wantHijack  bool
+		wantReader  bool
+	}{
+		{
+			name: "none",
+			w:    &plainWriter{httptest.NewRecorder()},
+		},
+		{
+			name: "Flusher",
+			w: &struct {
+				*plainWriter
+				testFlusher
+			}{&plainWriter{httptest.NewRecorder()}, testFlusher{}},
+			wantFlusher: true,
+		},
+		{
+			name: "Hijacker",
+			w: &struct {
+				*plainWriter
+				testHijacker
+			}{&plainWriter{httptest.NewRecorder()}, testHijacker{}},
+			wantHijack: true,
+		},
+		{
+			name: "ReaderFrom",
+			w: &struct {
+				*plainWriter
+				testReaderFrom
+			}{&plainWriter{httptest.NewRecorder()}, testReaderFrom{}},
+			wantReader: true,
+		},
+		{
+			name: "Flusher Hijacker",
+			w: &struct {
+				*plainWriter
+				testFlusher
+				testHijacker
+			}{&plainWriter{httptest.NewRecorder()}, testFlusher{}, testHijacker{}},
+			wantFlusher: true,
+			wantHijack:  true,
+		},
+		{
+			name: "Flusher ReaderFrom",
+			w: &struct {
+				*plainWriter
+				testFlusher
+				testReaderFrom
+			}{&plainWriter{httptest.NewRecorder()}, testFlusher{}, testReaderFrom{}},
+			wantFlusher: true,
+			wantReader:  true,
+		},
+		{
+			name: "Hijacker ReaderFrom",
+			w: &struct {
+				*plainWriter
+				testHijacker
+				testReaderFrom
+			}{&plainWriter{httptest.NewRecorder()}, testHijacker{}, testReaderFrom{}},
+			wantHijack: true,
+			wantReader: true,
+		},
+		{
+			name: "all",
+			w: &struct {
+				*plainWriter
+				testFlusher
+				testHijacker
+				testReaderFrom
+			}{&plainWriter{httptest.NewRecorder()}, testFlusher{}, testHijacker{}, testReaderFrom{}},
+			wantFlusher: true,
+			wantHijack:  true,
+			wantReader:  true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, sw := wrapWriter(tt.w)
+			if _, ok := got.(http.Flusher); ok != tt.wantFlusher {
+				t.Errorf("wrapWriter() Flusher = %v, want %v", ok, tt.wantFlusher)
+			}
+			if _, ok := got.(http.Hijacker); ok != tt.wantHijack {
+				t.Errorf("wrapWriter() Hijacker = %v, want %v", ok, tt.wantHijack)
+			}
+			if _, ok := got.(io.ReaderFrom); ok != tt.wantReader {
+				t.Errorf("wrapWriter() ReaderFrom = %v, want %v", ok, tt.wantReader)
+			}
+			u, ok := got.(interface{ Unwrap() http.ResponseWriter })
+			if !ok {
+				t.Fatalf("wrapWriter() does not implement Unwrap()")
+			}
+			if u.Unwrap() != tt.w {
+				t.Errorf("Unwrap() = %v, want %v", u.Unwrap(), tt.w)
+			}
+			if sw.ResponseWriter != tt.w {
+				t.Errorf("statusWriter.ResponseWriter = %v, want %v", sw.ResponseWriter, tt.w)
+			}
+		})
+	}
+}
+
+func Test_statusWriter_Flush(t *testing.T) {
+	t.Parallel()
+
+	rec := httptest.NewRecorder()
+	w, sw := wrapWriter(rec)
+
+	if err := http.NewResponseController(w).Flush(); err != nil {
+		t.Fatalf("ResponseController.Flush() error = %v", err)
+	}
+	if !rec.Flushed {
+		t.Errorf("ResponseRecorder.Flushed = %v, want %v", rec.Flushed, true)
+	}
+	if got := sw.Status(); got != http.StatusOK {
+		t.Errorf("statusWriter.Status() = %v, want %v", got, http.StatusOK)
+	}
+}
+
+func Test_statusWriter_ReadFrom(t *testing.T) {
+	t.Parallel()
+
+	rf := &struct {
+		*plainWriter
+		testReaderFrom
+	}{&plainWriter{httptest.NewRecorder()}, testReaderFrom{}}
+	w, sw := wrapWriter(rf)
+
+	n, err := io.Copy(w, io.LimitReader(strings.NewReader("0123456789"), 100))
+	if err != nil {
+		t.Fatalf("io.Copy() error = %v", err)
+	}
+	if n != 10 {
+		t.Errorf("io.Copy() = %v, want %v", n, 10)
+	}
+	if got := sw.Length(); got != 10 {
+		t.Errorf("statusWriter.Length() = %v, want %v", got, 10)
+	}
+	if got := sw.Status(); got != http.StatusOK {
+		t.Errorf("statusWriter.Status() = %v, want %v", got, http.StatusOK)
+	}
+}
+
+func Test_statusWriter_errors(t *testing.T) {
+	t.Parallel()
+
+	// Errors of the underlying ResponseWriter are returned unchanged, so they can still be compared
+	w, _ := wrapWriter(&struct {
+		*plainWriter
+		testHijacker
+		errReaderFrom
+	}{&plainWriter{httptest.NewRecorder()}, testHijacker{}, errReaderFrom{}})
+
+	if _, _, err := w.(http.Hijacker).Hijack(); !errors.Is(err, http.ErrHijacked) || err.Error() != http.ErrHijacked.Error() {
+		t.Errorf("Hijack() error = %v, want %v", err, http.ErrHijacked)
+	}
+	if _, err := w.(io.ReaderFrom).ReadFrom(strings.NewReader("hello")); !errors.Is(err, io.ErrClosedPipe) || err.Error() != io.ErrClosedPipe.Error() {
+		t.Errorf("ReadFrom() error = %v, want %v", err, io.ErrClosedPipe)
+	}
+}
+
+func Test_statusWriter_Hijack(t *testing.T) {
+	t.Parallel()
+
+	const resp = "HTTP/1.1 101 Switching Protocols\r\n\r\nhello"
+
+	s := &captureSink{}
+	done := make(chan struct{})
+	h := NewSinkExporter(s).Middleware()(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(time.Minute)); err != nil {
+				t.Errorf("ResponseController.SetWriteDeadline() error = %v", err)
+			}
+
+			conn, brw, err := http.NewResponseController(w).Hijack()
+			if err != nil {
+				t.Errorf("ResponseController.Hijack() error = %v", err)
+
+				return
+			}
+			defer conn.Close()
+
+			_, _ = brw.WriteString(resp[:10])
+			_ = brw.Flush()
+			_, _ = conn.Write([]byte(resp[10:]))
+		},
+	))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r)
+		close(done)
+	}))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("net.Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	_, _ = conn.Write([]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+	b, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("io.ReadAll() error = %v", err)
+	}
+	if string(b) != resp {
+		t.Errorf("response = %q, want %q", b, resp)
+	}
+
+	<-done
+	if len(s.parents) != 1 {
+		t.Fatalf("parents = %d, want 1", len(s.parents))
+	}
+	p := s.parents[0]
+	if p.Request.Status != http.StatusSwitchingProtocols {
+		t.Errorf("Status = %v, want %v", p.Request.Status, http.StatusSwitchingProtocols)
+	}
+	if p.Request.ResponseSize != int64(len(resp)) {
+		t.Errorf("ResponseSize = %v, want %v", p.Request.ResponseSize, len(resp))
+	}
+	if diff := deep.Equal(p.Fields, []Field{{Key: "hijacked", Value: true}}); diff != nil {
+		t.Errorf("Fields = %v", diff)
+	}
+}
+
+// plainWriter implements only the http.ResponseWriter interface
+type plainWriter struct {
+	http.ResponseWriter
+}
+
+type testFlusher struct{}
+
+func (testFlusher) Flush() {}
+
+type testHijacker struct{}
+
+func (testHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, http.ErrHijacked
+}
+
+type testReaderFrom struct{}
+
+func (testReaderFrom) ReadFrom(src io.Reader) (int64, error) {
+	return io.Copy(io.Discard, src)
+}
+
+// errReaderFrom fails after reading from src
+type errReaderFrom struct{}
+
+func (errReaderFrom) ReadFrom(src io.Reader) (int64, error) {
+	n, _ := io.Copy(io.Discard, src)
+
+	return n, io.ErrClosedPipe
+}
+
+func Test_countingBody_Read(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "read error", err: errors.New("connection reset")},
+		{name: "truncated body", err: io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := &countingBody{ReadCloser: io.NopCloser(io.MultiReader(strings.NewReader("hello"), iotest.ErrReader(tt.err)))}
+			n, err := io.Copy(io.Discard, b)
+			if n != 5 || b.n.Load() != 5 {
+				t.Errorf("countingBody.Read() n = %v, counted %v, want 5", n, b.n.Load())
+			}
+			// The error is returned unchanged, so handlers can compare it and show it to clients
+			if !errors.Is(err, tt.err) || err.Error() != tt.err.Error() {
+				t.Errorf("countingBody.Read() error = %v, want %v", err, tt.err)
+			}
+			if b.eof.Load() {
+				t.Errorf("countingBody.Read() eof = true, want false")
+			}
+		})
+	}
+}
+
+func Test_defaultParentMessage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		latency time.Duration
+		want    string
+	}{
+		{name: "milliseconds", latency: 1234567 * time.Nanosecond, want: "POST /orders 503 1ms"},
+		{name: "sub millisecond", latency: 123456 * time.Nanosecond, want: "POST /orders 503 123µs"},
+		{name: "seconds", latency: 1500 * time.Millisecond, want: "POST /orders 503 1.5s"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := &HTTPRequest{
+				Request: httptest.NewRequest(http.MethodPost, "/orders", http.NoBody),
+				Status:  http.StatusServiceUnavailable,
+				Latency: tt.latency,
+			}
+			if got := defaultParentMessage(req); got != tt.want {
+				t.Errorf("defaultParentMessage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
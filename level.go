@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"cloud.google.com/go/logging"
+)
+
+// maxLevelBody is the largest request body accepted by LevelVar.ServeHTTP
+const maxLevelBody = 64
+
+// LevelVar is a minimum severity that can be changed while the server is running. Logs with a severity
+// below the level are dropped. Default is the lowest severity, so logs written with logging.Default are
+// dropped by any level above it, including Debug. The zero value logs everything, and a LevelVar is safe
+// for concurrent use.
+//
+// LevelVar implements http.Handler: a GET returns the current level, and a PUT with a severity name
+// (e.g. "debug" or "warning") in the body changes it.
+type LevelVar struct {
+	v atomic.Int32
+}
+
+// NewLevelVar returns a LevelVar set to l
+func NewLevelVar(l logging.Severity) *LevelVar {
+	v := &LevelVar{}
+	v.Set(l)
+
+	return v
+}
+
+// Level returns the minimum severity
+func (v *LevelVar) Level() logging.Severity {
+	return logging.Severity(v.v.Load())
+}
+
+// Set changes the minimum severity to l
+func (v *LevelVar) Set(l logging.Severity) {
+	v.v.Store(int32(l))
+}
+
+// enabled reports if a log with severity s should be written
+func (v *LevelVar) enabled(s logging.Severity) bool {
+	return v == nil || s >= v.Level()
+}
+
+// ServeHTTP reports the level for a GET, and changes it for a PUT
+func (v *LevelVar) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPut:
+		b, err := io.ReadAll(io.LimitReader(r.Body, maxLevelBody))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		name := strings.TrimSpace(string(b))
+		l := logging.ParseSeverity(name)
+		if l == logging.Default && !strings.EqualFold(name, logging.Default.String()) {
+			http.Error(w, fmt.Sprintf("unknown severity %q", name), http.StatusBadRequest)
+
+			return
+		}
+		v.Set(l)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = fmt.Fprintln(w, v.Level())
+}
@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/logging"
+)
+
+func TestNewLevelVar(t *testing.T) {
+	t.Parallel()
+
+	v := NewLevelVar(logging.Warning)
+	if got := v.Level(); got != logging.Warning {
+		t.Errorf("LevelVar.Level() = %v, want %v", got, logging.Warning)
+	}
+	v.Set(logging.Debug)
+	if got := v.Level(); got != logging.Debug {
+		t.Errorf("LevelVar.Level() = %v, want %v", got, logging.Debug)
+	}
+}
+
+func TestLevelVar_enabled(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		v        *LevelVar
+		severity logging.Severity
+		want     bool
+	}{
+		{name: "nil", severity: logging.Debug, want: true},
+		{name: "zero value", v: &LevelVar{}, severity: logging.Debug, want: true},
+		{name: "below", v: NewLevelVar(logging.Info), severity: logging.Debug, want: false},
+		{name: "equal", v: NewLevelVar(logging.Info), severity: logging.Info, want: true},
+		{name: "above", v: NewLevelVar(logging.Info), severity: logging.Error, want: true},
+		{name: "default below debug", v: NewLevelVar(logging.Debug), severity: logging.Default, want: false},
+		{name: "default at default", v: NewLevelVar(logging.Default), severity: logging.Default, want: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.v.enabled(tt.severity); got != tt.want {
+				t.Errorf("LevelVar.enabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLevelVar_ServeHTTP(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		method    string
+		body      string
+		wantCode  int
+		wantBody  string
+		wantLevel logging.Severity
+	}{
+		{
+			name:      "GET",
+			method:    http.MethodGet,
+			wantCode:  http.StatusOK,
+			wantBody:  "Info\n",
+			wantLevel: logging.Info,
+		},
+		{
+			name:      "PUT",
+			method:    http.MethodPut,
+			body:      "debug\n",
+			wantCode:  http.StatusOK,
+			wantBody:  "Debug\n",
+			wantLevel: logging.Debug,
+		},
+		{
+			name:      "PUT default",
+			method:    http.MethodPut,
+			body:      "DEFAULT",
+			wantCode:  http.StatusOK,
+			wantBody:  "Default\n",
+			wantLevel: logging.Default,
+		},
+		{
+			name:      "PUT unknown",
+			method:    http.MethodPut,
+			body:      "verbose",
+			wantCode:  http.StatusBadRequest,
+			wantBody:  "unknown severity \"verbose\"\n",
+			wantLevel: logging.Info,
+		},
+		{
+			name:      "POST",
+			method:    http.MethodPost,
+			body:      "debug",
+			wantCode:  http.StatusMethodNotAllowed,
+			wantBody:  "Method Not Allowed\n",
+			wantLevel: logging.Info,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			v := NewLevelVar(logging.Info)
+			w := httptest.NewRecorder()
+			v.ServeHTTP(w, httptest.NewRequest(tt.method, "/level", strings.NewReader(tt.body)))
+
+			if w.Code != tt.wantCode {
+				t.Errorf("LevelVar.ServeHTTP() code = %v, want %v", w.Code, tt.wantCode)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("LevelVar.ServeHTTP() body = %q, want %q", got, tt.wantBody)
+			}
+			if got := v.Level(); got != tt.wantLevel {
+				t.Errorf("LevelVar.Level() = %v, want %v", got, tt.wantLevel)
+			}
+		})
+	}
+}
@@ -2,20 +2,45 @@
 // Each HTTP request is logged as the parent log, with all logs generated during the request as child logs.
 //
 // The Logging destination is configured with an Exporter. This package provides Exporters for Google Cloud Logging
-// and Console Logging.
+// and Console Logging. To export to any other destination, implement a Sink and use NewSinkExporter.
 //
 // The GoogleCloudExporter will also correlate logs to Cloud Trace if you instrument your code with tracing.
+// Trace IDs are read from the X-Cloud-Trace-Context, W3C traceparent and B3 headers, in an order set with WithPropagators.
+// Use WithServerSpan to start a server span for each request, so logs and Cloud Trace line up even when the request arrives without a trace.
+// On Cloud Run and GKE, NewGoogleCloudJSONExporter writes the same logs as structured JSON to stdout.
+//
+// The same Option values configure every Exporter through its Options method.
+// Set a minimum severity with WithLevel and a LevelVar. A LevelVar is also an http.Handler, so the level can be changed while the server is running.
+// To write all logs for a single request, configure WithDebugSecret and send a token from NewDebugToken in the X-Debug-Log header.
+//
+// Using WithBuffer, the logs written during a request are only exported if the request fails, logs an error, or is slower than WithSlowRequest.
+// Set a Sampler with WithSampler to log only a fraction of requests, for example with RatioSampler and KeepErrors.
+// Use WithExclude to skip the request log for health checks and metrics scrapes.
+// With Go 1.23 or later, the route matched by http.ServeMux is added to the request log, or set a RouteResolver with WithRouteResolver for other routers.
+// Handlers can also set it with SetRoute.
+// Child logs record the source location of their caller; disable it with WithSourceLocation, and use
+// AddCallerSkip in functions that wrap the Logger.
+// Errors created with github.com/go-playground/errors are logged with the source, tags and types of each wrap.
+// Use ErrorReporting to group error logs in Cloud Error Reporting.
+// For jobs and message consumers, StartOperation correlates logs the same way outside an HTTP request.
+// gRPC servers get the same logging with UnaryServerInterceptor and StreamServerInterceptor.
 package logger
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"runtime"
+
+	"cloud.google.com/go/logging"
 )
 
 // Logger implements logging methods for this package
 type Logger struct {
-	ctx context.Context
-	lg  ctxLogger
+	ctx    context.Context
+	lg     ctxLogger
+	fields []Field
+	skip   int
 }
 
 // Ctx returns the logger from the context. If
@@ -36,42 +61,185 @@ func Req(r *http.Request) *Logger {
 	}
 }
 
+// With returns a copy of the Logger that adds the key/value pairs to every log it writes.
+// Keys must be strings and are followed by their value. A Field may be passed in place of a
+// key/value pair.
+func (l *Logger) With(kv ...interface{}) *Logger {
+	return &Logger{
+		ctx:    l.ctx,
+		lg:     l.lg,
+		fields: appendFields(l.fields, kv),
+		skip:   l.skip,
+	}
+}
+
+// AddCallerSkip returns a copy of the Logger that skips n more stack frames when it records the
+// source location of a log. Use it in functions that wrap the Logger, so the location reported
+// is the caller of the wrapper.
+func (l *Logger) AddCallerSkip(n int) *Logger {
+	return &Logger{
+		ctx:    l.ctx,
+		lg:     l.lg,
+		fields: l.fields,
+		skip:   l.skip + n,
+	}
+}
+
+// SetRequestField adds the key/value pair to the request log, rather than to a log written
+// during the request. Setting a key again replaces its value. Use it for attributes such as
+// the user or tenant, that the request logs are filtered on.
+func (l *Logger) SetRequestField(key string, value interface{}) {
+	l.lg.SetRequestField(key, value)
+}
+
+// SetRoute sets the route template matched by the request, such as "/users/{id}", in place of the one
+// found by the middleware. Use it from inside the handler chain when the route is not visible to the
+// middleware, such as when a middleware between them replaces the request with http.Request.WithContext:
+//
+//	logger.Req(r).SetRoute(r.Pattern)
+func (l *Logger) SetRoute(route string) {
+	l.lg.SetRoute(route)
+}
+
 // Debug logs a debug message.
 func (l *Logger) Debug(v interface{}) {
-	l.lg.Debug(l.ctx, v)
+	l.log(logging.Debug, v, l.fields)
 }
 
 // Debugf logs a debug message with format.
 func (l *Logger) Debugf(format string, v ...interface{}) {
-	l.lg.Debugf(l.ctx, format, v...)
+	l.log(logging.Debug, fmt.Sprintf(format, v...), l.fields)
+}
+
+// Debugw logs a debug message with key/value pairs.
+func (l *Logger) Debugw(msg string, kv ...interface{}) {
+	l.log(logging.Debug, msg, appendFields(l.fields, kv))
 }
 
 // Info logs a info message.
 func (l *Logger) Info(v interface{}) {
-	l.lg.Info(l.ctx, v)
+	l.log(logging.Info, v, l.fields)
 }
 
 // Infof logs a info message with format.
 func (l *Logger) Infof(format string, v ...interface{}) {
-	l.lg.Infof(l.ctx, format, v...)
+	l.log(logging.Info, fmt.Sprintf(format, v...), l.fields)
+}
+
+// Infow logs a info message with key/value pairs.
+func (l *Logger) Infow(msg string, kv ...interface{}) {
+	l.log(logging.Info, msg, appendFields(l.fields, kv))
+}
+
+// Notice logs a notice message.
+func (l *Logger) Notice(v interface{}) {
+	l.log(logging.Notice, v, l.fields)
+}
+
+// Noticef logs a notice message with format.
+func (l *Logger) Noticef(format string, v ...interface{}) {
+	l.log(logging.Notice, fmt.Sprintf(format, v...), l.fields)
+}
+
+// Noticew logs a notice message with key/value pairs.
+func (l *Logger) Noticew(msg string, kv ...interface{}) {
+	l.log(logging.Notice, msg, appendFields(l.fields, kv))
 }
 
 // Warn logs a warning message.
 func (l *Logger) Warn(v interface{}) {
-	l.lg.Warn(l.ctx, v)
+	l.log(logging.Warning, v, l.fields)
 }
 
 // Warnf logs a warning message with format.
 func (l *Logger) Warnf(format string, v ...interface{}) {
-	l.lg.Warnf(l.ctx, format, v...)
+	l.log(logging.Warning, fmt.Sprintf(format, v...), l.fields)
+}
+
+// Warnw logs a warning message with key/value pairs.
+func (l *Logger) Warnw(msg string, kv ...interface{}) {
+	l.log(logging.Warning, msg, appendFields(l.fields, kv))
 }
 
 // Error logs an error message.
 func (l *Logger) Error(v interface{}) {
-	l.lg.Error(l.ctx, v)
+	l.log(logging.Error, v, l.fields)
 }
 
 // Errorf logs an error message with format.
 func (l *Logger) Errorf(format string, v ...interface{}) {
-	l.lg.Errorf(l.ctx, format, v...)
+	l.log(logging.Error, fmt.Sprintf(format, v...), l.fields)
+}
+
+// Errorw logs an error message with key/value pairs.
+func (l *Logger) Errorw(msg string, kv ...interface{}) {
+	l.log(logging.Error, msg, appendFields(l.fields, kv))
+}
+
+// Critical logs a critical message.
+func (l *Logger) Critical(v interface{}) {
+	l.log(logging.Critical, v, l.fields)
+}
+
+// Criticalf logs a critical message with format.
+func (l *Logger) Criticalf(format string, v ...interface{}) {
+	l.log(logging.Critical, fmt.Sprintf(format, v...), l.fields)
+}
+
+// Criticalw logs a critical message with key/value pairs.
+func (l *Logger) Criticalw(msg string, kv ...interface{}) {
+	l.log(logging.Critical, msg, appendFields(l.fields, kv))
+}
+
+// Alert logs an alert message.
+func (l *Logger) Alert(v interface{}) {
+	l.log(logging.Alert, v, l.fields)
+}
+
+// Alertf logs an alert message with format.
+func (l *Logger) Alertf(format string, v ...interface{}) {
+	l.log(logging.Alert, fmt.Sprintf(format, v...), l.fields)
+}
+
+// Alertw logs an alert message with key/value pairs.
+func (l *Logger) Alertw(msg string, kv ...interface{}) {
+	l.log(logging.Alert, msg, appendFields(l.fields, kv))
+}
+
+// Emergency logs an emergency message.
+func (l *Logger) Emergency(v interface{}) {
+	l.log(logging.Emergency, v, l.fields)
+}
+
+// Emergencyf logs an emergency message with format.
+func (l *Logger) Emergencyf(format string, v ...interface{}) {
+	l.log(logging.Emergency, fmt.Sprintf(format, v...), l.fields)
+}
+
+// Emergencyw logs an emergency message with key/value pairs.
+func (l *Logger) Emergencyw(msg string, kv ...interface{}) {
+	l.log(logging.Emergency, msg, appendFields(l.fields, kv))
+}
+
+// Log logs a message with severity. A log with logging.Default severity is dropped by any LevelVar
+// above Default, including Debug.
+func (l *Logger) Log(severity logging.Severity, v interface{}) {
+	l.log(severity, v, l.fields)
+}
+
+// Logf logs a message with severity and format.
+func (l *Logger) Logf(severity logging.Severity, format string, v ...interface{}) {
+	l.log(severity, fmt.Sprintf(format, v...), l.fields)
+}
+
+// Logw logs a message with severity and key/value pairs.
+func (l *Logger) Logw(severity logging.Severity, msg string, kv ...interface{}) {
+	l.log(severity, msg, appendFields(l.fields, kv))
+}
+
+// log writes a log with the source location of the caller of the Logger method
+func (l *Logger) log(severity logging.Severity, v interface{}, fields []Field) {
+	var pcs [1]uintptr
+	runtime.Callers(callerSkip+l.skip, pcs[:])
+	l.lg.Log(l.ctx, severity, v, fields, pcs[0])
 }
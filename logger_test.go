@@ -6,6 +6,9 @@ import (
 	"errors"
 	"net/http"
 	"testing"
+
+	"cloud.google.com/go/logging"
+	"github.com/go-test/deep"
 )
 
 func TestLogger(t *testing.T) {
@@ -64,11 +67,11 @@ func TestLogger(t *testing.T) {
 			t.Parallel()
 
 			var buf bytes.Buffer
-			ctx := newContext(context.Background(), &gcpLogger{
-				lg: &testLogger{
+			ctx := newContext(context.Background(), newRequestLogger(&gcpSink{
+				childLogger: &testLogger{
 					buf: &buf,
 				},
-			})
+			}, &http.Request{}, ""))
 
 			r := &http.Request{}
 			r = r.WithContext(ctx)
@@ -127,3 +130,104 @@ func TestLogger(t *testing.T) {
 		})
 	}
 }
+
+func TestLogger_With(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		with []interface{}
+		kv   []interface{}
+	}
+	tests := []struct {
+		name        string
+		args        args
+		wantPayload map[string]interface{}
+	}{
+		{
+			name: "no fields",
+			wantPayload: map[string]interface{}{
+				"message": "Message",
+			},
+		},
+		{
+			name: "With fields",
+			args: args{
+				with: []interface{}{"tenant", "acme"},
+			},
+			wantPayload: map[string]interface{}{
+				"message": "Message",
+				"tenant":  "acme",
+			},
+		},
+		{
+			name: "With and key/value fields",
+			args: args{
+				with: []interface{}{"tenant", "acme"},
+				kv:   []interface{}{"order_id", 123},
+			},
+			wantPayload: map[string]interface{}{
+				"message":  "Message",
+				"tenant":   "acme",
+				"order_id": 123,
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := &captureLogger{}
+			ctx := newContext(context.Background(), newRequestLogger(&gcpSink{childLogger: c}, &http.Request{}, ""))
+			l := Ctx(ctx).With(tt.args.with...)
+
+			logs := []struct {
+				name     string
+				log      func()
+				severity logging.Severity
+			}{
+				{name: "Debugw", log: func() { l.Debugw("Message", tt.args.kv...) }, severity: logging.Debug},
+				{name: "Infow", log: func() { l.Infow("Message", tt.args.kv...) }, severity: logging.Info},
+				{name: "Warnw", log: func() { l.Warnw("Message", tt.args.kv...) }, severity: logging.Warning},
+				{name: "Errorw", log: func() { l.Errorw("Message", tt.args.kv...) }, severity: logging.Error},
+			}
+			for _, lg := range logs {
+				lg.log()
+				if c.e.Severity != lg.severity {
+					t.Errorf("Logger.%s() severity = %v, want %v", lg.name, c.e.Severity, lg.severity)
+				}
+				if diff := deep.Equal(c.e.Payload, tt.wantPayload); diff != nil {
+					t.Errorf("Logger.%s() payload = %v", lg.name, diff)
+				}
+			}
+
+			if len(tt.args.kv) != 0 {
+				return
+			}
+
+			logs = []struct {
+				name     string
+				log      func()
+				severity logging.Severity
+			}{
+				{name: "Debug", log: func() { l.Debug("Message") }, severity: logging.Debug},
+				{name: "Debugf", log: func() { l.Debugf("%s", "Message") }, severity: logging.Debug},
+				{name: "Info", log: func() { l.Info("Message") }, severity: logging.Info},
+				{name: "Infof", log: func() { l.Infof("%s", "Message") }, severity: logging.Info},
+				{name: "Warn", log: func() { l.Warn("Message") }, severity: logging.Warning},
+				{name: "Warnf", log: func() { l.Warnf("%s", "Message") }, severity: logging.Warning},
+				{name: "Error", log: func() { l.Error("Message") }, severity: logging.Error},
+				{name: "Errorf", log: func() { l.Errorf("%s", "Message") }, severity: logging.Error},
+			}
+			for _, lg := range logs {
+				lg.log()
+				if c.e.Severity != lg.severity {
+					t.Errorf("Logger.%s() severity = %v, want %v", lg.name, c.e.Severity, lg.severity)
+				}
+				if diff := deep.Equal(c.e.Payload, tt.wantPayload); diff != nil {
+					t.Errorf("Logger.%s() payload = %v", lg.name, diff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/logging"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startOperation injects a Logger writing to sink into ctx, and returns a func that writes the log of the
+// operation when it ends. It is the equivalent of the request handler for work outside an HTTP request.
+func (c config) startOperation(ctx context.Context, sink Sink, name string) (context.Context, func(err error)) {
+	begin := time.Now()
+	var span trace.Span
+	if c.tracerProvider != nil {
+		ctx, span = c.tracerProvider.Tracer(tracerName).Start(ctx, name, trace.WithTimestamp(begin))
+	}
+
+	l := newRequestLogger(sink, nil, traceIDFromContext(ctx))
+	l.operation = name
+	l.level = c.level
+	l.source = !c.noSource
+	l.stack = c.errorStack
+	l.buffer = c.buffer
+	ctx = newContext(ctx, l)
+
+	var once sync.Once
+
+	return ctx, func(err error) {
+		once.Do(func() {
+			c.endOperation(ctx, l, begin, err)
+			if span != nil {
+				if err != nil {
+					span.RecordError(err)
+					span.SetStatus(codes.Error, err.Error())
+				}
+				span.End()
+			}
+		})
+	}
+}
+
+// endOperation writes the logs held by l and the log of the operation
+func (c config) endOperation(ctx context.Context, l *requestLogger, begin time.Time, err error) {
+	latency := time.Since(begin)
+	failed := err != nil
+	fields := l.flush(!c.buffer || failed || l.debug || (c.slowRequest > 0 && latency >= c.slowRequest))
+
+	l.mu.Lock()
+	logCount := l.logCount
+	maxSeverity := l.maxSeverity
+	requestFields := l.requestFields
+	l.mu.Unlock()
+
+	if !c.logAll && logCount == 0 && !failed {
+		return
+	}
+
+	outcome := "success"
+	severity := maxSeverity
+	if failed {
+		outcome = "failure"
+		if severity < logging.Error {
+			severity = logging.Error
+		}
+	}
+	// There is no response status, so only the latency applies
+	if s := c.severityPolicy.Severity(0, latency); severity < s {
+		severity = s
+	}
+
+	fields = append(fields, Field{Key: "operation", Value: []Field{
+		{Key: "name", Value: l.operation},
+		{Key: "outcome", Value: outcome},
+		{Key: "duration", Value: latency.String()},
+		{Key: "max_severity", Value: maxSeverity.String()},
+		{Key: "log_count", Value: logCount},
+	}})
+	if err != nil {
+		fields = append(fields, Field{Key: "error", Value: err})
+	}
+	fields = append(fields, requestFields...)
+
+	sc := trace.SpanFromContext(ctx).SpanContext()
+
+	l.sink.WriteParent(&Record{
+		Time:         begin,
+		Severity:     severity,
+		Message:      l.operation + " " + outcome + " " + roundLatency(latency).String(),
+		Fields:       fields,
+		TraceID:      l.traceID,
+		SpanID:       sc.SpanID().String(),
+		TraceSampled: sc.IsSampled(),
+		Operation:    l.operation,
+	})
+}
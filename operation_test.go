@@ -0,0 +1,201 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"regexp"
+	"testing"
+
+	"cloud.google.com/go/logging"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSinkExporter_StartOperation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		logAll       bool
+		buffer       bool
+		log          func(l *Logger)
+		err          error
+		wantParent   bool
+		wantSeverity logging.Severity
+		wantOutcome  string
+		wantChildren int
+	}{
+		{
+			name:         "success",
+			logAll:       true,
+			log:          func(l *Logger) { l.Info("one"); l.Warn("two") },
+			wantParent:   true,
+			wantSeverity: logging.Warning,
+			wantOutcome:  "success",
+			wantChildren: 2,
+		},
+		{
+			name:         "failure",
+			logAll:       true,
+			log:          func(l *Logger) { l.Info("one") },
+			err:          errors.New("boom"),
+			wantParent:   true,
+			wantSeverity: logging.Error,
+			wantOutcome:  "failure",
+			wantChildren: 1,
+		},
+		{
+			name: "no logs",
+			log:  func(*Logger) {},
+		},
+		{
+			name:         "no logs failure",
+			log:          func(*Logger) {},
+			err:          errors.New("boom"),
+			wantParent:   true,
+			wantSeverity: logging.Error,
+			wantOutcome:  "failure",
+		},
+		{
+			name:         "buffered success",
+			logAll:       true,
+			buffer:       true,
+			log:          func(l *Logger) { l.Info("one") },
+			wantParent:   true,
+			wantSeverity: logging.Info,
+			wantOutcome:  "success",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &captureSink{}
+			ctx, end := NewSinkExporter(s).
+				LogAll(tt.logAll).
+				Options(WithBuffer(tt.buffer)).
+				StartOperation(context.Background(), "sync-orders")
+			tt.log(Ctx(ctx))
+			end(tt.err)
+			end(nil)
+
+			if len(s.children) != tt.wantChildren {
+				t.Errorf("children = %d, want %d", len(s.children), tt.wantChildren)
+			}
+			if !tt.wantParent {
+				if len(s.parents) != 0 {
+					t.Errorf("parents = %d, want 0", len(s.parents))
+				}
+
+				return
+			}
+			if len(s.parents) != 1 {
+				t.Fatalf("parents = %d, want 1", len(s.parents))
+			}
+
+			p := s.parents[0]
+			if p.Severity != tt.wantSeverity {
+				t.Errorf("parent Severity = %v, want %v", p.Severity, tt.wantSeverity)
+			}
+			if p.Operation != "sync-orders" || p.Request != nil {
+				t.Errorf("parent Operation = %v, Request = %v, want sync-orders and nil", p.Operation, p.Request)
+			}
+			if msg, _ := p.Message.(string); !regexp.MustCompile(`^sync-orders ` + tt.wantOutcome + ` \S+$`).MatchString(msg) {
+				t.Errorf("parent Message = %v, want sync-orders %v with a duration", msg, tt.wantOutcome)
+			}
+			var op []Field
+			for _, f := range p.Fields {
+				if f.Key == "operation" {
+					op, _ = f.Value.([]Field)
+				}
+			}
+			if len(op) != 5 || op[1].Value != tt.wantOutcome {
+				t.Errorf("parent operation = %v, want outcome %v", op, tt.wantOutcome)
+			}
+			for _, c := range s.children {
+				if c.TraceID != p.TraceID || c.Operation != "sync-orders" || c.Request != nil {
+					t.Errorf("child = %+v, want the trace and operation of the parent", c)
+				}
+			}
+		})
+	}
+}
+
+func TestSinkExporter_StartOperation_ServerSpan(t *testing.T) {
+	t.Parallel()
+
+	sr := tracetest.NewSpanRecorder()
+	s := &captureSink{}
+	ctx, end := NewSinkExporter(s).
+		Options(WithServerSpan(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))).
+		StartOperation(context.Background(), "sync-orders")
+	Ctx(ctx).Info("one")
+	end(errors.New("boom"))
+
+	spans := sr.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("spans = %d, want 1", len(spans))
+	}
+	if spans[0].Name() != "sync-orders" || spans[0].Status().Code != codes.Error {
+		t.Errorf("span = %v %v, want sync-orders with an error status", spans[0].Name(), spans[0].Status())
+	}
+	if tid := spans[0].SpanContext().TraceID().String(); s.parents[0].TraceID != tid || s.children[0].TraceID != tid {
+		t.Errorf("TraceID = %v, want %v", s.parents[0].TraceID, tid)
+	}
+	if sid := spans[0].SpanContext().SpanID().String(); s.children[0].SpanID != sid {
+		t.Errorf("child SpanID = %v, want %v", s.children[0].SpanID, sid)
+	}
+}
+
+func TestConsoleExporter_StartOperation(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	ctx, end := NewConsoleExporter().NoColor(true).Options(WithSourceLocation(false)).StartOperation(context.Background(), "sync-orders")
+	Ctx(ctx).Infow("synced", "count", 3)
+	end(nil)
+
+	want := `^.{20}INFO : sync-orders synced count=3\n` +
+		`.{20}OK   : sync-orders \S+ operation\.max_severity=Info operation\.log_count=1\n$`
+	if s := buf.String(); !regexp.MustCompile(want).MatchString(s) {
+		t.Errorf("ConsoleExporter.StartOperation() output = %q, want match %q", s, want)
+	}
+}
+
+func TestConsoleExporter_StartOperation_outcome(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "success without logs",
+			want: `^.{20}OK   : job \S+ operation\.max_severity=Default operation\.log_count=0\n$`,
+		},
+		{
+			name: "failure",
+			err:  errors.New("boom"),
+			want: `^.{20}FAIL : job \S+ operation\.max_severity=Default operation\.log_count=0 error=boom\n$`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log.SetOutput(&buf)
+			t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+			_, end := NewConsoleExporter().NoColor(true).StartOperation(context.Background(), "job")
+			end(tt.err)
+
+			if s := buf.String(); !regexp.MustCompile(tt.want).MatchString(s) {
+				t.Errorf("ConsoleExporter.StartOperation() output = %q, want match %q", s, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package logger
+
+import (
+	"net/netip"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Option configures the request logging of an exporter. The same Options apply to every exporter,
+// and are set with the Options method of the exporter.
+type Option func(c *config)
+
+// WithPropagators sets the Propagators used to find the trace of a request, in order of precedence.
+// The first Propagator to extract a trace ID from the request headers wins. When none is found,
+// the trace in the request context is used. Calling WithPropagators with no arguments restores the default.
+// (default: CloudTracePropagator, W3CPropagator, B3SinglePropagator, B3MultiPropagator)
+func WithPropagators(p ...Propagator) Option {
+	return func(c *config) {
+		c.propagators = p
+	}
+}
+
+// WithLevel sets the minimum severity of the logs written during a request. Logs below the level are dropped,
+// and are not counted towards the request log. A nil v writes all logs (default: nil)
+func WithLevel(v *LevelVar) Option {
+	return func(c *config) {
+		c.level = v
+	}
+}
+
+// WithDebugSecret sets the secret used to verify the token in the DebugHeader of a request. All logs
+// of a request with a valid token are written, regardless of WithLevel, and the request log is
+// marked with a debug field. Create tokens with NewDebugToken. A nil secret disables debug tokens (default: nil)
+func WithDebugSecret(secret []byte) Option {
+	return func(c *config) {
+		c.debugSecret = secret
+	}
+}
+
+// WithBuffer controls if the logs written during a request are held until the request completes. They are
+// only written if the request fails with a 5xx status or a panic, has a log at Error or above, has a valid
+// debug token, or is slower than WithSlowRequest. Otherwise they are dropped, and the request log has a dropped_logs field
+// with the number dropped of each severity (default: false)
+func WithBuffer(v bool) Option {
+	return func(c *config) {
+		c.buffer = v
+	}
+}
+
+// WithSlowRequest sets the latency above which the buffered logs of a request are written. A zero d
+// disables the latency check. It has no effect unless WithBuffer is enabled (default: 0)
+func WithSlowRequest(d time.Duration) Option {
+	return func(c *config) {
+		c.slowRequest = d
+	}
+}
+
+// WithSampler sets the Sampler that decides if a request is logged once it has completed. Logs written
+// during the request are held until the decision is made. A nil s logs every request (default: nil)
+func WithSampler(s Sampler) Option {
+	return func(c *config) {
+		c.sampler = s
+	}
+}
+
+// WithExclude sets the Exclusions for requests that are not logged, such as health checks. The first
+// Exclusion to match a request is used (default: none)
+func WithExclude(ex ...Exclusion) Option {
+	return func(c *config) {
+		c.exclusions = ex
+	}
+}
+
+// WithTrustedProxies sets the address ranges of the proxies trusted to report the client IP in the
+// Forwarded, X-Forwarded-For and X-Real-IP headers. Calling WithTrustedProxies with no arguments restores
+// the default (default: loopback, private and link-local ranges)
+func WithTrustedProxies(prefixes ...netip.Prefix) Option {
+	return func(c *config) {
+		c.trustedProxies = prefixes
+	}
+}
+
+// WithTrustedHops sets the number of addresses at the end of the Forwarded or X-Forwarded-For header that
+// are trusted, whatever they are. Use it for proxies that append an address of their own that is not known
+// in advance, such as the forwarding rule IP of a Google Cloud Load Balancer. The hops are only trusted when
+// the connection is from one of the trusted proxies (default: 0)
+func WithTrustedHops(n int) Option {
+	return func(c *config) {
+		c.trustedHops = n
+	}
+}
+
+// WithSeverityPolicy sets the policy that raises the severity of the request log from the response status
+// and latency. A nil p uses DefaultSeverityPolicy (default: nil)
+func WithSeverityPolicy(p *SeverityPolicy) Option {
+	return func(c *config) {
+		c.severityPolicy = p
+	}
+}
+
+// WithParentMessage sets the function that returns the message of the request log. A nil f uses
+// the default, such as "GET /orders/123 200 12ms" (default: nil)
+func WithParentMessage(f MessageFunc) Option {
+	return func(c *config) {
+		c.parentMessage = f
+	}
+}
+
+// WithRouteResolver sets the function that returns the route template matched by a request, for routers
+// other than http.ServeMux. A nil f uses the pattern matched by http.ServeMux, when built with
+// Go 1.23 or later (default: nil)
+func WithRouteResolver(f RouteResolver) Option {
+	return func(c *config) {
+		c.routeResolver = f
+	}
+}
+
+// WithSourceLocation controls if the location in the code that wrote each log is recorded (default: true)
+func WithSourceLocation(v bool) Option {
+	return func(c *config) {
+		c.noSource = !v
+	}
+}
+
+// WithServerSpan controls if a server span is started with tp for each request. The span is put in the request
+// context, so logs written during the request are correlated to it, and is ended after the request log is
+// written. A nil tp disables the server span (default: nil)
+func WithServerSpan(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = tp
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !go1.23
+
+package logger
+
+import "net/http"
+
+// servePattern returns an empty string, as http.Request.Pattern was added in Go 1.23
+func servePattern(*http.Request) string {
+	return ""
+}
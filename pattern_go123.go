@@ -0,0 +1,11 @@
+//go:build go1.23
+
+package logger
+
+import "net/http"
+
+// servePattern returns the pattern matched by http.ServeMux for the request, if any. ServeMux only
+// sets it when the main module is Go 1.22 or later, or runs with GODEBUG=httpmuxgo121=0.
+func servePattern(r *http.Request) string {
+	return r.Pattern
+}
@@ -0,0 +1,122 @@
+//go:build go1.23
+
+// The go directive of the module predates the ServeMux patterns, so enable them for the tests
+//go:debug httpmuxgo121=0
+
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSinkExporter_RouteResolver(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		resolver    RouteResolver
+		pattern     string
+		withContext bool
+		setRoute    bool
+		want        string
+	}{
+		{
+			name:    "ServeMux pattern",
+			pattern: "/users/{id}",
+			want:    "/users/{id}",
+		},
+		{
+			name:    "ServeMux pattern with method",
+			pattern: "GET /users/{id}",
+			want:    "/users/{id}",
+		},
+		{
+			name:     "RouteResolver",
+			resolver: func(*http.Request) string { return "/users/:id" },
+			pattern:  "/users/{id}",
+			want:     "/users/:id",
+		},
+		{
+			name:        "ServeMux pattern behind WithContext",
+			pattern:     "/users/{id}",
+			withContext: true,
+		},
+		{
+			name:        "SetRoute behind WithContext",
+			pattern:     "/users/{id}",
+			withContext: true,
+			setRoute:    true,
+			want:        "/users/{id}",
+		},
+		{
+			name:     "SetRoute over RouteResolver",
+			resolver: func(*http.Request) string { return "/users/:id" },
+			pattern:  "/users/{id}",
+			setRoute: true,
+			want:     "/users/{id}",
+		},
+		{
+			name: "no route",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			sr := tracetest.NewSpanRecorder()
+			s := &captureSink{}
+			var next http.Handler = http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				if tt.setRoute {
+					Req(r).SetRoute(r.Pattern)
+				}
+			})
+			if tt.pattern != "" {
+				mux := http.NewServeMux()
+				mux.Handle(tt.pattern, next)
+				next = mux
+			}
+			if tt.withContext {
+				inner := next
+				next = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					inner.ServeHTTP(w, r.WithContext(r.Context()))
+				})
+			}
+			h := NewSinkExporter(s).
+				Options(WithRouteResolver(tt.resolver), WithServerSpan(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))).
+				Middleware()(next)
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/123", http.NoBody))
+
+			if len(s.parents) != 1 {
+				t.Fatalf("parents = %d, want 1", len(s.parents))
+			}
+			p := s.parents[0]
+			if p.Request.Route != tt.want {
+				t.Errorf("parent Route = %v, want %v", p.Request.Route, tt.want)
+			}
+
+			wantName := "GET /users/123"
+			var wantFields []Field
+			if tt.want != "" {
+				wantName = "GET " + tt.want
+				wantFields = []Field{{Key: "route", Value: tt.want}}
+			}
+			if diff := deep.Equal(p.Fields, wantFields); diff != nil {
+				t.Errorf("parent Fields = %v", diff)
+			}
+			if got, _ := p.Message.(string); !strings.HasPrefix(got, wantName+" 200 ") {
+				t.Errorf("parent Message = %v, want prefix %v", got, wantName+" 200 ")
+			}
+			if spans := sr.Ended(); len(spans) != 1 || spans[0].Name() != wantName {
+				t.Errorf("spans = %v, want one named %v", spans, wantName)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package logger
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	cloudTraceHeader   = "X-Cloud-Trace-Context"
+	traceparentHeader  = "Traceparent"
+	tracestateHeader   = "Tracestate"
+	b3Header           = "B3"
+	b3TraceIDHeader    = "X-B3-Traceid"
+	b3SpanIDHeader     = "X-B3-Spanid"
+	b3SampledHeader    = "X-B3-Sampled"
+	b3FlagsHeader      = "X-B3-Flags"
+	b3TraceIDShortSize = 16
+)
+
+// Propagator extracts the trace context propagated in the headers of a request
+type Propagator interface {
+	// Extract returns the trace context found in h. If h does not contain
+	// a valid trace context, the returned trace.SpanContext is not valid.
+	Extract(h http.Header) trace.SpanContext
+}
+
+// CloudTracePropagator extracts the trace context from the X-Cloud-Trace-Context header
+// set by Google Cloud load balancers and the Google Front End
+type CloudTracePropagator struct{}
+
+// Extract returns the trace context found in the X-Cloud-Trace-Context header
+func (CloudTracePropagator) Extract(h http.Header) trace.SpanContext {
+	// Format: TRACE_ID/SPAN_ID;o=TRACE_TRUE where SPAN_ID is decimal
+	v := h.Get(cloudTraceHeader)
+	traceStr, rest, _ := strings.Cut(v, "/")
+	spanStr, opts, _ := strings.Cut(rest, ";")
+
+	tid, err := trace.TraceIDFromHex(traceStr)
+	if err != nil {
+		return trace.SpanContext{}
+	}
+
+	var sid trace.SpanID
+	if id, err := strconv.ParseUint(spanStr, 10, 64); err == nil {
+		for i := range sid {
+			sid[i] = byte(id >> (8 * (len(sid) - 1 - i)))
+		}
+	}
+
+	var flags trace.TraceFlags
+	if opts == "o=1" {
+		flags = trace.FlagsSampled
+	}
+
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    tid,
+		SpanID:     sid,
+		TraceFlags: flags,
+		Remote:     true,
+	})
+}
+
+// W3CPropagator extracts the trace context from the W3C Trace Context traceparent and tracestate headers
+type W3CPropagator struct{}
+
+// Extract returns the trace context found in the traceparent and tracestate headers
+func (W3CPropagator) Extract(h http.Header) trace.SpanContext {
+	// Format: VERSION-TRACE_ID-PARENT_ID-FLAGS
+	parts := strings.Split(h.Get(traceparentHeader), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return trace.SpanContext{}
+	}
+
+	tid, err := trace.TraceIDFromHex(parts[1])
+	if err != nil {
+		return trace.SpanContext{}
+	}
+	sid, err := trace.SpanIDFromHex(parts[2])
+	if err != nil {
+		return trace.SpanContext{}
+	}
+	if len(parts[3]) != 2 {
+		return trace.SpanContext{}
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return trace.SpanContext{}
+	}
+
+	// An invalid tracestate is dropped without discarding the traceparent
+	ts, _ := trace.ParseTraceState(h.Get(tracestateHeader))
+
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    tid,
+		SpanID:     sid,
+		TraceFlags: trace.TraceFlags(flags) & trace.FlagsSampled,
+		TraceState: ts,
+		Remote:     true,
+	})
+}
+
+// B3SinglePropagator extracts the trace context from the single b3 header used by Zipkin
+type B3SinglePropagator struct{}
+
+// Extract returns the trace context found in the b3 header
+func (B3SinglePropagator) Extract(h http.Header) trace.SpanContext {
+	// Format: TRACE_ID-SPAN_ID-SAMPLING_STATE-PARENT_SPAN_ID, where the last two are optional
+	parts := strings.Split(h.Get(b3Header), "-")
+	if len(parts) < 2 || len(parts) > 4 {
+		return trace.SpanContext{}
+	}
+
+	var sampled string
+	if len(parts) > 2 {
+		sampled = parts[2]
+	}
+
+	return b3SpanContext(parts[0], parts[1], sampled == "1" || sampled == "d")
+}
+
+// B3MultiPropagator extracts the trace context from the X-B3-* headers used by Zipkin
+type B3MultiPropagator struct{}
+
+// Extract returns the trace context found in the X-B3-TraceId, X-B3-SpanId, X-B3-Sampled and X-B3-Flags headers
+func (B3MultiPropagator) Extract(h http.Header) trace.SpanContext {
+	sampled := h.Get(b3SampledHeader)
+
+	return b3SpanContext(
+		h.Get(b3TraceIDHeader),
+		h.Get(b3SpanIDHeader),
+		sampled == "1" || sampled == "true" || h.Get(b3FlagsHeader) == "1",
+	)
+}
+
+// b3SpanContext returns the trace context for B3 encoded IDs. 64 bit trace IDs are left padded with zeros.
+func b3SpanContext(traceStr, spanStr string, sampled bool) trace.SpanContext {
+	if len(traceStr) == b3TraceIDShortSize {
+		traceStr = strings.Repeat("0", b3TraceIDShortSize) + traceStr
+	}
+
+	tid, err := trace.TraceIDFromHex(traceStr)
+	if err != nil {
+		return trace.SpanContext{}
+	}
+	sid, err := trace.SpanIDFromHex(spanStr)
+	if err != nil {
+		return trace.SpanContext{}
+	}
+
+	var flags trace.TraceFlags
+	if sampled {
+		flags = trace.FlagsSampled
+	}
+
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    tid,
+		SpanID:     sid,
+		TraceFlags: flags,
+		Remote:     true,
+	})
+}
+
+// defaultPropagators returns the Propagators used when none are configured
+func defaultPropagators() []Propagator {
+	return []Propagator{
+		CloudTracePropagator{},
+		W3CPropagator{},
+		B3SinglePropagator{},
+		B3MultiPropagator{},
+	}
+}
@@ -0,0 +1,191 @@
+package logger
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPropagator_Extract(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		propagator  Propagator
+		headers     map[string]string
+		wantTraceID string
+		wantSpanID  string
+		wantSampled bool
+		wantState   string
+	}{
+		{
+			name:        "cloud trace",
+			propagator:  CloudTracePropagator{},
+			headers:     map[string]string{"X-Cloud-Trace-Context": "105445aa7843bc8bf206b12000100000/1;o=1"},
+			wantTraceID: "105445aa7843bc8bf206b12000100000",
+			wantSpanID:  "0000000000000001",
+			wantSampled: true,
+		},
+		{
+			name:        "cloud trace not sampled",
+			propagator:  CloudTracePropagator{},
+			headers:     map[string]string{"X-Cloud-Trace-Context": "105445aa7843bc8bf206b12000100000/18446744073709551615;o=0"},
+			wantTraceID: "105445aa7843bc8bf206b12000100000",
+			wantSpanID:  "ffffffffffffffff",
+		},
+		{
+			name:        "cloud trace without span",
+			propagator:  CloudTracePropagator{},
+			headers:     map[string]string{"X-Cloud-Trace-Context": "105445aa7843bc8bf206b12000100000"},
+			wantTraceID: "105445aa7843bc8bf206b12000100000",
+			wantSpanID:  "0000000000000000",
+		},
+		{
+			name:       "cloud trace invalid",
+			propagator: CloudTracePropagator{},
+			headers:    map[string]string{"X-Cloud-Trace-Context": "not-a-trace/1;o=1"},
+		},
+		{
+			name:       "cloud trace missing",
+			propagator: CloudTracePropagator{},
+		},
+		{
+			name:       "w3c",
+			propagator: W3CPropagator{},
+			headers: map[string]string{
+				"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+				"tracestate":  "congo=t61rcWkgMzE",
+			},
+			wantTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantSpanID:  "00f067aa0ba902b7",
+			wantSampled: true,
+			wantState:   "congo=t61rcWkgMzE",
+		},
+		{
+			name:        "w3c future version",
+			propagator:  W3CPropagator{},
+			headers:     map[string]string{"traceparent": "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra"},
+			wantTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantSpanID:  "00f067aa0ba902b7",
+		},
+		{
+			name:        "w3c invalid tracestate",
+			propagator:  W3CPropagator{},
+			headers:     map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "tracestate": "=="},
+			wantTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantSpanID:  "00f067aa0ba902b7",
+			wantSampled: true,
+		},
+		{
+			name:       "w3c version ff",
+			propagator: W3CPropagator{},
+			headers:    map[string]string{"traceparent": "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		},
+		{
+			name:       "w3c version 00 extra fields",
+			propagator: W3CPropagator{},
+			headers:    map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+		},
+		{
+			name:       "w3c zero trace ID",
+			propagator: W3CPropagator{},
+			headers:    map[string]string{"traceparent": "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		},
+		{
+			name:       "w3c bad flags",
+			propagator: W3CPropagator{},
+			headers:    map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz"},
+		},
+		{
+			name:        "b3 single",
+			propagator:  B3SinglePropagator{},
+			headers:     map[string]string{"b3": "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1-05e3ac9a4f6e3b90"},
+			wantTraceID: "80f198ee56343ba864fe8b2a57d3eff7",
+			wantSpanID:  "e457b5a2e4d86bd1",
+			wantSampled: true,
+		},
+		{
+			name:        "b3 single 64 bit trace ID",
+			propagator:  B3SinglePropagator{},
+			headers:     map[string]string{"b3": "a3ce929d0e0e4736-e457b5a2e4d86bd1"},
+			wantTraceID: "0000000000000000a3ce929d0e0e4736",
+			wantSpanID:  "e457b5a2e4d86bd1",
+		},
+		{
+			name:        "b3 single debug",
+			propagator:  B3SinglePropagator{},
+			headers:     map[string]string{"b3": "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-d"},
+			wantTraceID: "80f198ee56343ba864fe8b2a57d3eff7",
+			wantSpanID:  "e457b5a2e4d86bd1",
+			wantSampled: true,
+		},
+		{
+			name:       "b3 single sampling only",
+			propagator: B3SinglePropagator{},
+			headers:    map[string]string{"b3": "1"},
+		},
+		{
+			name:       "b3 multi",
+			propagator: B3MultiPropagator{},
+			headers: map[string]string{
+				"X-B3-TraceId": "80f198ee56343ba864fe8b2a57d3eff7",
+				"X-B3-SpanId":  "e457b5a2e4d86bd1",
+				"X-B3-Sampled": "1",
+			},
+			wantTraceID: "80f198ee56343ba864fe8b2a57d3eff7",
+			wantSpanID:  "e457b5a2e4d86bd1",
+			wantSampled: true,
+		},
+		{
+			name:       "b3 multi debug flag",
+			propagator: B3MultiPropagator{},
+			headers: map[string]string{
+				"X-B3-TraceId": "a3ce929d0e0e4736",
+				"X-B3-SpanId":  "e457b5a2e4d86bd1",
+				"X-B3-Flags":   "1",
+			},
+			wantTraceID: "0000000000000000a3ce929d0e0e4736",
+			wantSpanID:  "e457b5a2e4d86bd1",
+			wantSampled: true,
+		},
+		{
+			name:       "b3 multi missing span ID",
+			propagator: B3MultiPropagator{},
+			headers:    map[string]string{"X-B3-TraceId": "80f198ee56343ba864fe8b2a57d3eff7"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := http.Header{}
+			for k, v := range tt.headers {
+				h.Set(k, v)
+			}
+
+			sc := tt.propagator.Extract(h)
+			if tt.wantTraceID == "" {
+				if sc.TraceID().IsValid() {
+					t.Errorf("Extract() TraceID = %v, want invalid", sc.TraceID())
+				}
+
+				return
+			}
+			if got := sc.TraceID().String(); got != tt.wantTraceID {
+				t.Errorf("Extract() TraceID = %v, want %v", got, tt.wantTraceID)
+			}
+			if got := sc.SpanID().String(); got != tt.wantSpanID {
+				t.Errorf("Extract() SpanID = %v, want %v", got, tt.wantSpanID)
+			}
+			if got := sc.IsSampled(); got != tt.wantSampled {
+				t.Errorf("Extract() IsSampled = %v, want %v", got, tt.wantSampled)
+			}
+			if got := sc.TraceState().String(); got != tt.wantState {
+				t.Errorf("Extract() TraceState = %v, want %v", got, tt.wantState)
+			}
+			if !sc.IsRemote() {
+				t.Errorf("Extract() IsRemote = false, want true")
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package logger
+
+import (
+	"encoding/binary"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/logging"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SampleInfo describes a completed request for a Sampler
+type SampleInfo struct {
+	// Request is the http.Request passed to the handler. For a gRPC call, it is a POST request to the
+	// full method, with the metadata as headers.
+	Request *http.Request
+	// Route is the route template matched by the request, such as "/users/{id}", or empty if unknown.
+	// For a gRPC call, it is the full method, such as "/grpc.health.v1.Health/Check".
+	Route string
+	// Status is the response status code
+	Status int
+	// Latency is the time taken to serve the request
+	Latency time.Duration
+	// MaxSeverity is the highest severity of the logs written during the request
+	MaxSeverity logging.Severity
+	// TraceID is the hex encoded trace ID of the request
+	TraceID string
+	// TraceSampled reports if the trace of the request was sampled
+	TraceSampled bool
+}
+
+// SamplingResult is the decision of a Sampler
+type SamplingResult struct {
+	// Sample reports if the request is logged
+	Sample bool
+	// Rate is the fraction of similar requests that are logged, used to extrapolate counts. It is 0 if unknown.
+	Rate float64
+	// Reason names the policy that made the decision
+	Reason string
+}
+
+// Sampler decides if a request is logged once it has completed. The request log and all logs written
+// during a sampled out request are dropped. Requests with a valid debug token are always logged, without
+// consulting the Sampler. A Sampler must be safe for concurrent use.
+type Sampler interface {
+	Sample(info *SampleInfo) SamplingResult
+}
+
+// SamplerFunc is an adapter to allow the use of ordinary functions as a Sampler
+type SamplerFunc func(info *SampleInfo) SamplingResult
+
+// Sample calls f(info)
+func (f SamplerFunc) Sample(info *SampleInfo) SamplingResult {
+	return f(info)
+}
+
+// RatioSampler returns a Sampler that logs the given fraction of requests. The decision is made from the
+// trace ID, so services using the same ratio log the same requests.
+func RatioSampler(ratio float64) Sampler {
+	return SamplerFunc(func(info *SampleInfo) SamplingResult {
+		return SamplingResult{Sample: sampleTraceID(info.TraceID, ratio), Rate: ratio, Reason: "ratio"}
+	})
+}
+
+// RouteRatioSampler returns a Sampler that logs the fraction of requests given for their route in ratios,
+// and the fraction fallback of requests to any other route. Requests with an unknown route are matched
+// by their path.
+func RouteRatioSampler(ratios map[string]float64, fallback float64) Sampler {
+	return SamplerFunc(func(info *SampleInfo) SamplingResult {
+		route := info.Route
+		if route == "" {
+			route = info.Request.URL.Path
+		}
+		ratio, ok := ratios[route]
+		if !ok {
+			ratio = fallback
+		}
+
+		return SamplingResult{Sample: sampleTraceID(info.TraceID, ratio), Rate: ratio, Reason: "route"}
+	})
+}
+
+// KeepErrors returns a Sampler that logs every request with an error status or a log at Error or above,
+// and defers to next for all other requests.
+func KeepErrors(next Sampler) Sampler {
+	return SamplerFunc(func(info *SampleInfo) SamplingResult {
+		if info.Status > 399 || info.MaxSeverity >= logging.Error {
+			return SamplingResult{Sample: true, Rate: 1, Reason: "error"}
+		}
+
+		return next.Sample(info)
+	})
+}
+
+// TraceSampler returns a Sampler that follows the sampling decision of the trace. rate is the fraction of
+// traces sampled upstream, recorded to extrapolate counts (0 if unknown).
+func TraceSampler(rate float64) Sampler {
+	return SamplerFunc(func(info *SampleInfo) SamplingResult {
+		return SamplingResult{Sample: info.TraceSampled, Rate: rate, Reason: "trace"}
+	})
+}
+
+// sampleTraceID deterministically samples the fraction ratio of trace IDs
+func sampleTraceID(traceID string, ratio float64) bool {
+	switch {
+	case ratio >= 1:
+		return true
+	case ratio <= 0:
+		return false
+	}
+
+	tid, err := trace.TraceIDFromHex(traceID)
+	if err != nil {
+		return false
+	}
+
+	// Same as the OpenTelemetry TraceIDRatioBased sampler
+	return binary.BigEndian.Uint64(tid[8:16])>>1 < uint64(ratio*(1<<63))
+}
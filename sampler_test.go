@@ -0,0 +1,203 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging"
+	"github.com/go-test/deep"
+)
+
+func TestSamplers(t *testing.T) {
+	t.Parallel()
+
+	// low has the lowest possible sampling value, high the highest
+	const (
+		low  = "4bf92f3577b34da60000000000000000"
+		high = "4bf92f3577b34da6ffffffffffffffff"
+	)
+	never := SamplerFunc(func(*SampleInfo) SamplingResult { return SamplingResult{Reason: "never"} })
+
+	tests := []struct {
+		name    string
+		sampler Sampler
+		info    SampleInfo
+		want    SamplingResult
+	}{
+		{
+			name:    "ratio sampled",
+			sampler: RatioSampler(0.5),
+			info:    SampleInfo{TraceID: low},
+			want:    SamplingResult{Sample: true, Rate: 0.5, Reason: "ratio"},
+		},
+		{
+			name:    "ratio not sampled",
+			sampler: RatioSampler(0.5),
+			info:    SampleInfo{TraceID: high},
+			want:    SamplingResult{Rate: 0.5, Reason: "ratio"},
+		},
+		{
+			name:    "ratio always",
+			sampler: RatioSampler(1),
+			info:    SampleInfo{TraceID: high},
+			want:    SamplingResult{Sample: true, Rate: 1, Reason: "ratio"},
+		},
+		{
+			name:    "ratio never",
+			sampler: RatioSampler(0),
+			info:    SampleInfo{TraceID: low},
+			want:    SamplingResult{Reason: "ratio"},
+		},
+		{
+			name:    "ratio invalid trace ID",
+			sampler: RatioSampler(0.5),
+			info:    SampleInfo{TraceID: "not a trace"},
+			want:    SamplingResult{Rate: 0.5, Reason: "ratio"},
+		},
+		{
+			name:    "route",
+			sampler: RouteRatioSampler(map[string]float64{"/healthz": 0}, 1),
+			info:    SampleInfo{Request: httptest.NewRequest(http.MethodGet, "/healthz", http.NoBody), TraceID: low},
+			want:    SamplingResult{Reason: "route"},
+		},
+		{
+			name:    "route template",
+			sampler: RouteRatioSampler(map[string]float64{"/orders/{id}": 0}, 1),
+			info:    SampleInfo{Request: httptest.NewRequest(http.MethodGet, "/orders/123", http.NoBody), Route: "/orders/{id}", TraceID: low},
+			want:    SamplingResult{Reason: "route"},
+		},
+		{
+			name:    "route fallback",
+			sampler: RouteRatioSampler(map[string]float64{"/healthz": 0}, 1),
+			info:    SampleInfo{Request: httptest.NewRequest(http.MethodGet, "/orders", http.NoBody), TraceID: low},
+			want:    SamplingResult{Sample: true, Rate: 1, Reason: "route"},
+		},
+		{
+			name:    "keep errors status",
+			sampler: KeepErrors(never),
+			info:    SampleInfo{Status: http.StatusNotFound},
+			want:    SamplingResult{Sample: true, Rate: 1, Reason: "error"},
+		},
+		{
+			name:    "keep errors severity",
+			sampler: KeepErrors(never),
+			info:    SampleInfo{Status: http.StatusOK, MaxSeverity: logging.Critical},
+			want:    SamplingResult{Sample: true, Rate: 1, Reason: "error"},
+		},
+		{
+			name:    "keep errors next",
+			sampler: KeepErrors(never),
+			info:    SampleInfo{Status: http.StatusOK, MaxSeverity: logging.Warning},
+			want:    SamplingResult{Reason: "never"},
+		},
+		{
+			name:    "trace sampled",
+			sampler: TraceSampler(0.1),
+			info:    SampleInfo{TraceSampled: true},
+			want:    SamplingResult{Sample: true, Rate: 0.1, Reason: "trace"},
+		},
+		{
+			name:    "trace not sampled",
+			sampler: TraceSampler(0.1),
+			want:    SamplingResult{Rate: 0.1, Reason: "trace"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.sampler.Sample(&tt.info); got != tt.want {
+				t.Errorf("Sampler.Sample() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSinkExporter_Sampler(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		sampler      Sampler
+		route        string
+		debug        bool
+		wantChildren int
+		wantFields   []Field
+	}{
+		{
+			name:    "sampled out",
+			sampler: RatioSampler(0),
+		},
+		{
+			name:    "route sampled out",
+			sampler: RouteRatioSampler(map[string]float64{"/orders/{id}": 0}, 1),
+			route:   "/orders/{id}",
+		},
+		{
+			name:         "debug token",
+			sampler:      RatioSampler(0),
+			debug:        true,
+			wantChildren: 2,
+			wantFields: []Field{
+				{Key: "sampling", Value: []Field{
+					{Key: "reason", Value: "debug"},
+					{Key: "rate", Value: 1.0},
+				}},
+				{Key: "debug", Value: true},
+			},
+		},
+		{
+			name:         "sampled",
+			sampler:      TraceSampler(0.25),
+			wantChildren: 2,
+			wantFields: []Field{{Key: "sampling", Value: []Field{
+				{Key: "reason", Value: "trace"},
+				{Key: "rate", Value: 0.25},
+			}}},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			secret := []byte("secret")
+			s := &captureSink{}
+			opts := []Option{WithSampler(tt.sampler), WithDebugSecret(secret)}
+			if tt.route != "" {
+				opts = append(opts, WithRouteResolver(func(*http.Request) string { return tt.route }))
+			}
+			h := NewSinkExporter(s).Options(opts...).Middleware()(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					Req(r).Info("info log")
+					Req(r).Error("error log")
+				},
+			))
+			r := httptest.NewRequest(http.MethodGet, "/orders/123", http.NoBody)
+			r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+			if tt.debug {
+				r.Header.Set(DebugHeader, NewDebugToken(secret, time.Now().Add(time.Minute)))
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if len(s.children) != tt.wantChildren {
+				t.Errorf("children = %d, want %d", len(s.children), tt.wantChildren)
+			}
+			if tt.wantFields == nil {
+				if len(s.parents) != 0 {
+					t.Errorf("parents = %d, want 0", len(s.parents))
+				}
+
+				return
+			}
+			if len(s.parents) != 1 {
+				t.Fatalf("parents = %d, want 1", len(s.parents))
+			}
+			if diff := deep.Equal(s.parents[0].Fields, tt.wantFields); diff != nil {
+				t.Errorf("parent Fields = %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/logging"
+)
+
+// SeverityPolicy sets the minimum severity of the request log from the response status and latency.
+// The request log is written at the higher of this severity and the highest severity of its child logs.
+type SeverityPolicy struct {
+	// ClientError is the severity of a response with a 4xx status
+	ClientError logging.Severity
+	// ServerError is the severity of a response with a 5xx status
+	ServerError logging.Severity
+	// Status overrides the severity of specific status codes
+	Status map[int]logging.Severity
+	// Slow is the latency above which the severity is raised to at least Warning. Zero disables it.
+	Slow time.Duration
+}
+
+// DefaultSeverityPolicy returns the SeverityPolicy used when none is configured. Responses with a
+// 4xx or 5xx status are logged as errors.
+func DefaultSeverityPolicy() *SeverityPolicy {
+	return &SeverityPolicy{
+		ClientError: logging.Error,
+		ServerError: logging.Error,
+	}
+}
+
+// Severity returns the minimum severity of the request log for a response. A nil policy
+// behaves as DefaultSeverityPolicy.
+func (p *SeverityPolicy) Severity(status int, latency time.Duration) logging.Severity {
+	if p == nil {
+		p = DefaultSeverityPolicy()
+	}
+
+	severity, ok := p.Status[status]
+	if !ok {
+		switch {
+		case status >= http.StatusInternalServerError:
+			severity = p.ServerError
+		case status >= http.StatusBadRequest:
+			severity = p.ClientError
+		}
+	}
+
+	if p.Slow > 0 && latency >= p.Slow && severity < logging.Warning {
+		severity = logging.Warning
+	}
+
+	return severity
+}
@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging"
+)
+
+func TestSeverityPolicy_Severity(t *testing.T) {
+	t.Parallel()
+
+	policy := &SeverityPolicy{
+		ClientError: logging.Warning,
+		ServerError: logging.Error,
+		Status: map[int]logging.Severity{
+			http.StatusNotFound:           logging.Default,
+			http.StatusServiceUnavailable: logging.Critical,
+		},
+		Slow: time.Second,
+	}
+
+	tests := []struct {
+		name    string
+		policy  *SeverityPolicy
+		status  int
+		latency time.Duration
+		want    logging.Severity
+	}{
+		{name: "default 2xx", status: http.StatusOK, want: logging.Default},
+		{name: "default 4xx", status: http.StatusNotFound, want: logging.Error},
+		{name: "default 5xx", status: http.StatusInternalServerError, want: logging.Error},
+		{name: "default slow", status: http.StatusOK, latency: time.Hour, want: logging.Default},
+		{name: "2xx", policy: policy, status: http.StatusOK, want: logging.Default},
+		{name: "4xx", policy: policy, status: http.StatusUnauthorized, want: logging.Warning},
+		{name: "5xx", policy: policy, status: http.StatusBadGateway, want: logging.Error},
+		{name: "override", policy: policy, status: http.StatusNotFound, want: logging.Default},
+		{name: "override 5xx", policy: policy, status: http.StatusServiceUnavailable, want: logging.Critical},
+		{name: "slow", policy: policy, status: http.StatusOK, latency: time.Second, want: logging.Warning},
+		{name: "slow error", policy: policy, status: http.StatusBadGateway, latency: time.Second, want: logging.Error},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.policy.Severity(tt.status, tt.latency); got != tt.want {
+				t.Errorf("SeverityPolicy.Severity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/logging"
+	"google.golang.org/grpc"
+)
+
+// Sink is a destination for logs. The request logging middleware calls WriteChild for each log
+// written during a request, and WriteParent once the request has completed.
+//
+// Implement Sink and use it with NewSinkExporter to export logs to a destination not supported
+// by this package. A Sink must be safe for concurrent use.
+type Sink interface {
+	// WriteParent writes the request log
+	WriteParent(rec *Record)
+	// WriteChild writes a log generated during the request
+	WriteChild(rec *Record)
+}
+
+// Record is a single log entry passed to a Sink
+type Record struct {
+	// Time is when the log was written. For the request log, it is the start of the request.
+	Time time.Time
+	// Severity is the level of the log. For the request log, it is the highest severity of all
+	// child logs, raised by the SeverityPolicy from the response status and latency.
+	Severity logging.Severity
+	// Message is the value logged, usually a string or an error
+	Message interface{}
+	// Fields holds the key/value pairs added to the log
+	Fields []Field
+	// TraceID is the hex encoded trace ID of the request
+	TraceID string
+	// SpanID is the hex encoded span ID active when the log was written
+	SpanID string
+	// TraceSampled reports if the trace was sampled
+	TraceSampled bool
+	// Request describes the HTTP request. For child logs only the Request, RemoteIP and User fields are set.
+	// It is nil for logs of an operation started with StartOperation, or of a gRPC call.
+	Request *HTTPRequest
+	// Operation is the name of the operation started with StartOperation, or the full method of a gRPC
+	// call. It is empty for logs of an HTTP request.
+	Operation string
+	// Stack is the stack trace of a recovered panic, or of an error log when ErrorReporting is enabled
+	Stack string
+	// Source is the location in the code that wrote the log, if known
+	Source *slog.Source
+}
+
+// HTTPRequest contains an http.Request and details of the response
+type HTTPRequest struct {
+	// Request is the http.Request passed to the handler
+	Request *http.Request
+	// RequestSize is the size of the request body in bytes
+	RequestSize int64
+	// Status is the response status code
+	Status int
+	// ResponseSize is the size of the response body in bytes
+	ResponseSize int64
+	// Latency is the time taken to serve the request
+	Latency time.Duration
+	// RemoteIP is the IP address of the client that issued the request
+	RemoteIP string
+	// Route is the route template matched by the request, such as "/users/{id}", if known
+	Route string
+	// User identifies the user that issued the request, from the "user" field set with SetRequestField
+	User string
+}
+
+// SinkExporter implements exporting to a Sink
+type SinkExporter struct {
+	sink Sink
+	config
+}
+
+// NewSinkExporter returns a configured SinkExporter
+func NewSinkExporter(s Sink) *SinkExporter {
+	return &SinkExporter{
+		sink:   s,
+		config: config{logAll: true},
+	}
+}
+
+// LogAll controls if this logger will log all requests, or only requests that contain
+// logs written to the request Logger (default: true)
+func (e *SinkExporter) LogAll(v bool) *SinkExporter {
+	e.logAll = v
+
+	return e
+}
+
+// Repanic controls if a panic in the handler is propagated after it is logged. When false,
+// the panic is recovered and a 500 Internal Server Error is sent if the response has not
+// been started (default: false)
+func (e *SinkExporter) Repanic(v bool) *SinkExporter {
+	e.repanic = v
+
+	return e
+}
+
+// Options applies opts to the request logging of the exporter
+func (e *SinkExporter) Options(opts ...Option) *SinkExporter {
+	for _, opt := range opts {
+		opt(&e.config)
+	}
+
+	return e
+}
+
+// UnaryServerInterceptor returns a gRPC interceptor that injects a Logger into the context of unary calls,
+// and writes a request log for each call. The SeverityPolicy applies to the HTTP status equivalent to the
+// gRPC status code of the call.
+func (e *SinkExporter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return (&grpcHandler{sink: e.sink, config: e.config}).unary
+}
+
+// StreamServerInterceptor returns a gRPC interceptor that injects a Logger into the context of streams,
+// and writes a request log for each stream.
+func (e *SinkExporter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return (&grpcHandler{sink: e.sink, config: e.config}).stream
+}
+
+// StartOperation starts logging work done outside an HTTP request, such as a job or a message consumer.
+// Logs written with Ctx to the returned context are correlated by a log of the operation, written with
+// its duration and outcome when end is called. A non-nil err marks the operation as failed.
+func (e *SinkExporter) StartOperation(ctx context.Context, name string) (context.Context, func(err error)) {
+	return e.startOperation(ctx, e.sink, name)
+}
+
+// Middleware returns a middleware that exports logs to the Sink
+func (e *SinkExporter) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return &requestHandler{
+			next:   next,
+			sink:   e.sink,
+			config: e.config,
+		}
+	}
+}
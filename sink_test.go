@@ -0,0 +1,888 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging"
+	"github.com/go-test/deep"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNewSinkExporter(t *testing.T) {
+	t.Parallel()
+
+	s := &captureSink{}
+	want := &SinkExporter{
+		sink:   s,
+		config: config{logAll: true},
+	}
+	if got := NewSinkExporter(s); !reflect.DeepEqual(got, want) {
+		t.Errorf("NewSinkExporter() = %v, want %v", got, want)
+	}
+}
+
+func TestSinkExporter_LogAll(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		v    bool
+		want *SinkExporter
+	}{
+		{
+			name: "logAll=true",
+			v:    true,
+			want: &SinkExporter{config: config{logAll: true}},
+		},
+		{
+			name: "logAll=false",
+			want: &SinkExporter{},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			e := &SinkExporter{config: config{logAll: !tt.v}}
+			if got := e.LogAll(tt.v); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SinkExporter.LogAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSinkExporter_Repanic(t *testing.T) {
+	t.Parallel()
+
+	for _, v := range []bool{true, false} {
+		e := &SinkExporter{config: config{repanic: !v}}
+		want := &SinkExporter{config: config{repanic: v}}
+		if got := e.Repanic(v); !reflect.DeepEqual(got, want) {
+			t.Errorf("SinkExporter.Repanic() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestSinkExporter_Middleware(t *testing.T) {
+	t.Parallel()
+
+	s := &captureSink{}
+	next := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	want := &requestHandler{
+		next:   next,
+		sink:   s,
+		config: config{logAll: true},
+	}
+	got := NewSinkExporter(s).Middleware()(next)
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("SinkExporter.Middleware() = %v", diff)
+	}
+}
+
+func TestSinkExporter_ServeHTTP(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		logAll       bool
+		logs         int
+		status       int
+		wantParent   bool
+		wantSeverity logging.Severity
+	}{
+		{
+			name:         "logAll=true no logging",
+			logAll:       true,
+			status:       http.StatusOK,
+			wantParent:   true,
+			wantSeverity: logging.Default,
+		},
+		{
+			name:   "logAll=false no logging",
+			status: http.StatusOK,
+		},
+		{
+			name:         "logAll=false with logging",
+			logs:         2,
+			status:       http.StatusOK,
+			wantParent:   true,
+			wantSeverity: logging.Warning,
+		},
+		{
+			name:         "error status",
+			logAll:       true,
+			status:       http.StatusNotFound,
+			wantParent:   true,
+			wantSeverity: logging.Error,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &captureSink{}
+			h := NewSinkExporter(s).LogAll(tt.logAll).Middleware()(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					for i := 0; i < tt.logs; i++ {
+						Req(r).Warnw("some log", "i", i)
+					}
+					w.WriteHeader(tt.status)
+					_, _ = w.Write([]byte("hello"))
+				},
+			))
+
+			r := httptest.NewRequest(http.MethodPost, "/orders", http.NoBody)
+			r.Header.Add("X-Cloud-Trace-Context", "105445aa7843bc8bf206b12000100000/1;o=1")
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if len(s.children) != tt.logs {
+				t.Fatalf("children = %d, want %d", len(s.children), tt.logs)
+			}
+			for i, c := range s.children {
+				if c.Message != "some log" {
+					t.Errorf("child Message = %v, want %v", c.Message, "some log")
+				}
+				if diff := deep.Equal(c.Fields, []Field{{Key: "i", Value: i}}); diff != nil {
+					t.Errorf("child Fields = %v", diff)
+				}
+				if c.TraceID != "105445aa7843bc8bf206b12000100000" {
+					t.Errorf("child TraceID = %v, want %v", c.TraceID, "105445aa7843bc8bf206b12000100000")
+				}
+				if c.Request.Request.URL.Path != "/orders" {
+					t.Errorf("child Request path = %v, want %v", c.Request.Request.URL.Path, "/orders")
+				}
+			}
+
+			if !tt.wantParent {
+				if len(s.parents) != 0 {
+					t.Errorf("parents = %d, want 0", len(s.parents))
+				}
+
+				return
+			}
+			if len(s.parents) != 1 {
+				t.Fatalf("parents = %d, want 1", len(s.parents))
+			}
+			p := s.parents[0]
+			if p.Severity != tt.wantSeverity {
+				t.Errorf("parent Severity = %v, want %v", p.Severity, tt.wantSeverity)
+			}
+			if p.TraceID != "105445aa7843bc8bf206b12000100000" {
+				t.Errorf("parent TraceID = %v, want %v", p.TraceID, "105445aa7843bc8bf206b12000100000")
+			}
+			if p.Request.Status != tt.status {
+				t.Errorf("parent Status = %v, want %v", p.Request.Status, tt.status)
+			}
+			if p.Request.ResponseSize != 5 {
+				t.Errorf("parent ResponseSize = %v, want %v", p.Request.ResponseSize, 5)
+			}
+		})
+	}
+}
+
+func TestSinkExporter_ServeHTTP_panic(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		repanic     bool
+		value       interface{}
+		wrote       bool
+		wantPanic   bool
+		wantChild   bool
+		wantRecStat int
+	}{
+		{
+			name:        "recovered",
+			value:       "boom",
+			wantChild:   true,
+			wantRecStat: http.StatusInternalServerError,
+		},
+		{
+			name:        "recovered after response started",
+			value:       "boom",
+			wrote:       true,
+			wantChild:   true,
+			wantRecStat: http.StatusAccepted,
+		},
+		{
+			name:        "repanic",
+			repanic:     true,
+			value:       "boom",
+			wantPanic:   true,
+			wantChild:   true,
+			wantRecStat: http.StatusOK,
+		},
+		{
+			name:        "http.ErrAbortHandler",
+			value:       http.ErrAbortHandler,
+			wantPanic:   true,
+			wantRecStat: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &captureSink{}
+			h := NewSinkExporter(s).Repanic(tt.repanic).Middleware()(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					if tt.wrote {
+						w.WriteHeader(http.StatusAccepted)
+					}
+					panic(tt.value)
+				},
+			))
+
+			rec := httptest.NewRecorder()
+			func() {
+				defer func() {
+					if p := recover(); (p != nil) != tt.wantPanic {
+						t.Errorf("recover() = %v, wantPanic %v", p, tt.wantPanic)
+					}
+				}()
+				h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+			}()
+
+			if rec.Code != tt.wantRecStat {
+				t.Errorf("response Code = %v, want %v", rec.Code, tt.wantRecStat)
+			}
+
+			if !tt.wantChild {
+				if len(s.children) != 0 {
+					t.Errorf("children = %d, want 0", len(s.children))
+				}
+			} else {
+				if len(s.children) != 1 {
+					t.Fatalf("children = %d, want 1", len(s.children))
+				}
+				c := s.children[0]
+				if c.Severity != logging.Error {
+					t.Errorf("child Severity = %v, want %v", c.Severity, logging.Error)
+				}
+				if c.Message != "panic: boom" {
+					t.Errorf("child Message = %v, want %v", c.Message, "panic: boom")
+				}
+				if !strings.Contains(c.Stack, "TestSinkExporter_ServeHTTP_panic") {
+					t.Errorf("child Stack = %v, want the panicking function", c.Stack)
+				}
+			}
+
+			if len(s.parents) != 1 {
+				t.Fatalf("parents = %d, want 1", len(s.parents))
+			}
+			p := s.parents[0]
+			if p.Severity != logging.Error {
+				t.Errorf("parent Severity = %v, want %v", p.Severity, logging.Error)
+			}
+			if p.Request.Status != http.StatusInternalServerError {
+				t.Errorf("parent Status = %v, want %v", p.Request.Status, http.StatusInternalServerError)
+			}
+		})
+	}
+}
+
+func TestSinkExporter_ServerSpan(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		traceparent string
+		cloudTrace  string
+		panic       bool
+		wantStatus  codes.Code
+		wantTraceID string
+	}{
+		{
+			name:       "new trace",
+			wantStatus: codes.Unset,
+		},
+		{
+			name:        "propagated trace",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			wantStatus:  codes.Unset,
+		},
+		{
+			name:        "cloud trace without span",
+			cloudTrace:  "105445aa7843bc8bf206b12000100000/;o=1",
+			wantStatus:  codes.Unset,
+			wantTraceID: "105445aa7843bc8bf206b12000100000",
+		},
+		{
+			name:        "cloud trace with span 0",
+			cloudTrace:  "105445aa7843bc8bf206b12000100000/0;o=1",
+			wantStatus:  codes.Unset,
+			wantTraceID: "105445aa7843bc8bf206b12000100000",
+		},
+		{
+			name:       "panic",
+			panic:      true,
+			wantStatus: codes.Error,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			sr := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+			s := &captureSink{}
+			h := NewSinkExporter(s).Options(WithServerSpan(tp)).Middleware()(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					Req(r).Info("some log")
+					if len(sr.Ended()) != 0 {
+						t.Errorf("span ended before the request completed")
+					}
+					if tt.panic {
+						panic("boom")
+					}
+				},
+			))
+
+			r := httptest.NewRequest(http.MethodGet, "/orders", http.NoBody)
+			if tt.traceparent != "" {
+				r.Header.Set("traceparent", tt.traceparent)
+			}
+			if tt.cloudTrace != "" {
+				r.Header.Set("X-Cloud-Trace-Context", tt.cloudTrace)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			spans := sr.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("spans = %d, want 1", len(spans))
+			}
+			span := spans[0]
+			if span.Name() != "GET /orders" {
+				t.Errorf("span Name = %v, want %v", span.Name(), "GET /orders")
+			}
+			if span.SpanKind() != trace.SpanKindServer {
+				t.Errorf("span SpanKind = %v, want %v", span.SpanKind(), trace.SpanKindServer)
+			}
+			if span.Status().Code != tt.wantStatus {
+				t.Errorf("span Status = %v, want %v", span.Status().Code, tt.wantStatus)
+			}
+			if tt.traceparent != "" {
+				if got := span.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+					t.Errorf("span Parent = %v, want %v", got, "00f067aa0ba902b7")
+				}
+				if got := span.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+					t.Errorf("span TraceID = %v, want %v", got, "4bf92f3577b34da6a3ce929d0e0e4736")
+				}
+			}
+
+			traceID := span.SpanContext().TraceID().String()
+			if tt.wantTraceID != "" && traceID != tt.wantTraceID {
+				t.Errorf("span TraceID = %v, want %v", traceID, tt.wantTraceID)
+			}
+			spanID := span.SpanContext().SpanID().String()
+			for _, rec := range append(s.children[:1:1], s.parents...) {
+				if rec.TraceID != traceID {
+					t.Errorf("Record TraceID = %v, want %v", rec.TraceID, traceID)
+				}
+				if rec.SpanID != spanID {
+					t.Errorf("Record SpanID = %v, want %v", rec.SpanID, spanID)
+				}
+			}
+		})
+	}
+}
+
+func TestSinkExporter_ServerSpan_noop(t *testing.T) {
+	t.Parallel()
+
+	s := &captureSink{}
+	h := NewSinkExporter(s).Options(WithServerSpan(trace.NewNoopTracerProvider())).Middleware()(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			Req(r).Info("some log")
+		},
+	))
+	for i := 0; i < 2; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders", http.NoBody))
+	}
+
+	if len(s.parents) != 2 {
+		t.Fatalf("parents = %d, want 2", len(s.parents))
+	}
+	for _, rec := range s.parents {
+		if _, err := trace.TraceIDFromHex(rec.TraceID); err != nil {
+			t.Errorf("Record TraceID = %v, want a valid trace ID", rec.TraceID)
+		}
+	}
+	if s.parents[0].TraceID == s.parents[1].TraceID {
+		t.Errorf("Record TraceID = %v for both requests, want a trace ID for each", s.parents[0].TraceID)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/orders", http.NoBody)
+	r.Header.Set("X-Cloud-Trace-Context", "105445aa7843bc8bf206b12000100000")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if got := s.parents[2].TraceID; got != "105445aa7843bc8bf206b12000100000" {
+		t.Errorf("Record TraceID = %v, want %v", got, "105445aa7843bc8bf206b12000100000")
+	}
+}
+
+func TestSinkExporter_Level(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		logAll       bool
+		level        logging.Severity
+		wantChildren int
+		wantParent   bool
+		wantSeverity logging.Severity
+	}{
+		{
+			name:         "all logs",
+			level:        logging.Default,
+			wantChildren: 2,
+			wantParent:   true,
+			wantSeverity: logging.Info,
+		},
+		{
+			name:         "debug filtered",
+			level:        logging.Info,
+			wantChildren: 1,
+			wantParent:   true,
+			wantSeverity: logging.Info,
+		},
+		{
+			name:         "all filtered logAll=true",
+			logAll:       true,
+			level:        logging.Warning,
+			wantParent:   true,
+			wantSeverity: logging.Default,
+		},
+		{
+			name:  "all filtered logAll=false",
+			level: logging.Warning,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &captureSink{}
+			h := NewSinkExporter(s).LogAll(tt.logAll).Options(WithLevel(NewLevelVar(tt.level))).Middleware()(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					Req(r).Debug("debug log")
+					Req(r).Info("info log")
+				},
+			))
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+
+			if len(s.children) != tt.wantChildren {
+				t.Errorf("children = %d, want %d", len(s.children), tt.wantChildren)
+			}
+			if !tt.wantParent {
+				if len(s.parents) != 0 {
+					t.Errorf("parents = %d, want 0", len(s.parents))
+				}
+
+				return
+			}
+			if len(s.parents) != 1 {
+				t.Fatalf("parents = %d, want 1", len(s.parents))
+			}
+			if got := s.parents[0].Severity; got != tt.wantSeverity {
+				t.Errorf("parent Severity = %v, want %v", got, tt.wantSeverity)
+			}
+		})
+	}
+}
+
+func TestSinkExporter_Buffer(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		slowRequest  time.Duration
+		status       int
+		severity     logging.Severity
+		sleep        time.Duration
+		wantChildren int
+		wantFields   []Field
+	}{
+		{
+			name:     "dropped",
+			status:   http.StatusOK,
+			severity: logging.Warning,
+			wantFields: []Field{{Key: "dropped_logs", Value: []Field{
+				{Key: "Debug", Value: 2},
+				{Key: "Warning", Value: 1},
+			}}},
+		},
+		{
+			name:         "server error",
+			status:       http.StatusServiceUnavailable,
+			severity:     logging.Warning,
+			wantChildren: 3,
+		},
+		{
+			name:         "error log",
+			status:       http.StatusOK,
+			severity:     logging.Error,
+			wantChildren: 3,
+		},
+		{
+			name:         "slow request",
+			slowRequest:  time.Millisecond,
+			sleep:        5 * time.Millisecond,
+			status:       http.StatusOK,
+			severity:     logging.Info,
+			wantChildren: 3,
+		},
+		{
+			name:        "fast request",
+			slowRequest: time.Hour,
+			status:      http.StatusOK,
+			severity:    logging.Info,
+			wantFields: []Field{{Key: "dropped_logs", Value: []Field{
+				{Key: "Debug", Value: 2},
+				{Key: "Info", Value: 1},
+			}}},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &captureSink{}
+			h := NewSinkExporter(s).Options(WithBuffer(true), WithSlowRequest(tt.slowRequest)).Middleware()(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					Req(r).Debug("debug log")
+					Req(r).Debug("debug log")
+					switch tt.severity {
+					case logging.Info:
+						Req(r).Info("some log")
+					case logging.Warning:
+						Req(r).Warn("some log")
+					default:
+						Req(r).Error("some log")
+					}
+					s.mu.Lock()
+					if len(s.children) != 0 {
+						t.Errorf("children written before the request completed")
+					}
+					s.mu.Unlock()
+					time.Sleep(tt.sleep)
+					w.WriteHeader(tt.status)
+				},
+			))
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+
+			if len(s.children) != tt.wantChildren {
+				t.Errorf("children = %d, want %d", len(s.children), tt.wantChildren)
+			}
+			if len(s.parents) != 1 {
+				t.Fatalf("parents = %d, want 1", len(s.parents))
+			}
+			if diff := deep.Equal(s.parents[0].Fields, tt.wantFields); diff != nil {
+				t.Errorf("parent Fields = %v", diff)
+			}
+		})
+	}
+}
+
+func TestSinkExporter_RequestSize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		body          io.Reader
+		contentLength string
+		read          int64
+		wantSize      int64
+		wantFields    []Field
+	}{
+		{
+			name:       "chunked body read",
+			body:       strings.NewReader("hello world"),
+			read:       -1,
+			wantSize:   11,
+			wantFields: []Field{{Key: "request_body_consumed", Value: true}},
+		},
+		{
+			name:          "body partly read",
+			body:          strings.NewReader("hello world"),
+			contentLength: "11",
+			read:          5,
+			wantSize:      5,
+			wantFields:    []Field{{Key: "request_body_consumed", Value: false}},
+		},
+		{
+			name:          "body not read",
+			body:          strings.NewReader("hello world"),
+			contentLength: "11",
+			wantSize:      11,
+			wantFields:    []Field{{Key: "request_body_consumed", Value: false}},
+		},
+		{
+			name: "no body",
+			body: http.NoBody,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &captureSink{}
+			h := NewSinkExporter(s).Middleware()(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					switch {
+					case tt.read < 0:
+						_, _ = io.Copy(io.Discard, r.Body)
+					case tt.read > 0:
+						_, _ = io.CopyN(io.Discard, r.Body, tt.read)
+					}
+				},
+			))
+			r := httptest.NewRequest(http.MethodPost, "/upload", tt.body)
+			if tt.contentLength != "" {
+				r.Header.Set("Content-Length", tt.contentLength)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if len(s.parents) != 1 {
+				t.Fatalf("parents = %d, want 1", len(s.parents))
+			}
+			if got := s.parents[0].Request.RequestSize; got != tt.wantSize {
+				t.Errorf("parent RequestSize = %v, want %v", got, tt.wantSize)
+			}
+			if diff := deep.Equal(s.parents[0].Fields, tt.wantFields); diff != nil {
+				t.Errorf("parent Fields = %v", diff)
+			}
+		})
+	}
+}
+
+func TestSinkExporter_SeverityPolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		status       int
+		sleep        time.Duration
+		wantSeverity logging.Severity
+	}{
+		{name: "ok", status: http.StatusOK, wantSeverity: logging.Info},
+		{name: "not found", status: http.StatusNotFound, wantSeverity: logging.Warning},
+		{name: "server error", status: http.StatusInternalServerError, wantSeverity: logging.Error},
+		{name: "slow", status: http.StatusOK, sleep: 5 * time.Millisecond, wantSeverity: logging.Warning},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &captureSink{}
+			policy := &SeverityPolicy{ClientError: logging.Warning, ServerError: logging.Error, Slow: time.Millisecond}
+			h := NewSinkExporter(s).Options(WithSeverityPolicy(policy)).Middleware()(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					Req(r).Info("some log")
+					time.Sleep(tt.sleep)
+					w.WriteHeader(tt.status)
+				},
+			))
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+
+			if len(s.parents) != 1 {
+				t.Fatalf("parents = %d, want 1", len(s.parents))
+			}
+			if got := s.parents[0].Severity; got != tt.wantSeverity {
+				t.Errorf("parent Severity = %v, want %v", got, tt.wantSeverity)
+			}
+		})
+	}
+}
+
+func TestSinkExporter_ParentMessage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		message MessageFunc
+		want    string
+	}{
+		{
+			name: "default",
+			want: "GET /orders/123 201 ",
+		},
+		{
+			name: "custom",
+			message: func(req *HTTPRequest) string {
+				return fmt.Sprintf("%d %s", req.Status, req.Request.URL.Path)
+			},
+			want: "201 /orders/123",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &captureSink{}
+			h := NewSinkExporter(s).Options(WithParentMessage(tt.message)).Middleware()(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusCreated)
+				},
+			))
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/123", http.NoBody))
+
+			if len(s.parents) != 1 {
+				t.Fatalf("parents = %d, want 1", len(s.parents))
+			}
+			if got, _ := s.parents[0].Message.(string); !strings.HasPrefix(got, tt.want) {
+				t.Errorf("parent Message = %v, want prefix %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogger_Log(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		log  func(l *Logger)
+		want logging.Severity
+	}{
+		{name: "Notice", log: func(l *Logger) { l.Notice("Message") }, want: logging.Notice},
+		{name: "Noticef", log: func(l *Logger) { l.Noticef("Message %d", 1) }, want: logging.Notice},
+		{name: "Criticalw", log: func(l *Logger) { l.Criticalw("Message", "k", "v") }, want: logging.Critical},
+		{name: "Alert", log: func(l *Logger) { l.Alert("Message") }, want: logging.Alert},
+		{name: "Emergencyf", log: func(l *Logger) { l.Emergencyf("Message %d", 1) }, want: logging.Emergency},
+		{name: "Log", log: func(l *Logger) { l.Log(logging.Critical, "Message") }, want: logging.Critical},
+		{name: "Logw below max", log: func(l *Logger) { l.Alert("Message"); l.Logw(logging.Notice, "Message", "k", "v") }, want: logging.Alert},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &captureSink{}
+			h := NewSinkExporter(s).Middleware()(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				tt.log(Req(r))
+			}))
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+
+			if len(s.parents) != 1 {
+				t.Fatalf("parents = %d, want 1", len(s.parents))
+			}
+			if got := s.parents[0].Severity; got != tt.want {
+				t.Errorf("parent Severity = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogger_SetRequestField(t *testing.T) {
+	t.Parallel()
+
+	s := &captureSink{}
+	h := NewSinkExporter(s).Middleware()(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			Req(r).SetRequestField("user_id", 1)
+			Req(r).SetRequestField("tenant", "acme")
+			Req(r).SetRequestField("user_id", 42)
+			Req(r).Info("some log")
+		},
+	))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+
+	if len(s.parents) != 1 {
+		t.Fatalf("parents = %d, want 1", len(s.parents))
+	}
+	want := []Field{{Key: "user_id", Value: 42}, {Key: "tenant", Value: "acme"}}
+	if diff := deep.Equal(s.parents[0].Fields, want); diff != nil {
+		t.Errorf("parent Fields = %v", diff)
+	}
+	if len(s.children) != 1 || s.children[0].Fields != nil {
+		t.Errorf("children = %v, want one without fields", s.children)
+	}
+}
+
+func TestSinkExporter_SourceLocation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		enabled bool
+	}{
+		{name: "enabled", enabled: true},
+		{name: "disabled", enabled: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &captureSink{}
+			h := NewSinkExporter(s).
+				Options(WithSourceLocation(tt.enabled)).
+				Middleware()(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				Req(r).Info("Message")
+				slog.New(NewSlogHandler()).InfoContext(r.Context(), "Message")
+			}))
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+
+			if len(s.children) != 2 {
+				t.Fatalf("children = %d, want 2", len(s.children))
+			}
+			for _, c := range s.children {
+				if !tt.enabled {
+					if c.Source != nil {
+						t.Errorf("child Source = %v, want nil", c.Source)
+					}
+
+					continue
+				}
+				if c.Source == nil {
+					t.Fatal("child Source = nil")
+				}
+				if filepath.Base(c.Source.File) != "sink_test.go" || !strings.Contains(c.Source.Function, "TestSinkExporter_SourceLocation") {
+					t.Errorf("child Source = %v, want the handler in this test", c.Source)
+				}
+			}
+		})
+	}
+}
+
+type captureSink struct {
+	mu       sync.Mutex
+	parents  []*Record
+	children []*Record
+}
+
+func (s *captureSink) WriteParent(rec *Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.parents = append(s.parents, rec)
+}
+
+func (s *captureSink) WriteChild(rec *Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.children = append(s.children, rec)
+}
@@ -0,0 +1,136 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+
+	"cloud.google.com/go/logging"
+)
+
+// SlogHandler implements slog.Handler, writing each record to the request logger found
+// in the record's context. Records logged with a request context are correlated to the
+// request log exactly like logs written with Ctx or Req.
+//
+// Attributes are written as fields, and groups as nested fields.
+type SlogHandler struct {
+	groups []string
+	// fields holds the attributes added to each open group, fields[0] being the top level
+	fields [][]Field
+}
+
+// NewSlogHandler returns a slog.Handler that writes to the request logger in the record's context
+func NewSlogHandler() *SlogHandler {
+	return &SlogHandler{fields: make([][]Field, 1)}
+}
+
+// Enabled reports whether the handler handles records at the given level.
+func (h *SlogHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle writes the record to the request logger in ctx.
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	var fields []Field
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, a)
+
+		return true
+	})
+
+	for i := len(h.groups) - 1; i >= 0; i-- {
+		g := h.fields[i+1]
+		if len(fields) != 0 {
+			g = append(g[:len(g):len(g)], fields...)
+		}
+		fields = nil
+		if len(g) != 0 {
+			fields = []Field{{Key: h.groups[i], Value: g}}
+		}
+	}
+	fields = append(h.fields[0][:len(h.fields[0]):len(h.fields[0])], fields...)
+
+	fromCtx(ctx).Log(ctx, slogSeverity(r.Level), r.Message, fields, r.PC)
+
+	return nil
+}
+
+// WithAttrs returns a new handler whose attributes consist of both the receiver's attributes and the arguments.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	h2 := h.clone()
+	last := len(h2.fields) - 1
+	for _, a := range attrs {
+		h2.fields[last] = appendAttr(h2.fields[last], a)
+	}
+
+	return h2
+}
+
+// WithGroup returns a new handler with the given group appended to the receiver's existing groups.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	h2 := h.clone()
+	h2.groups = append(h2.groups, name)
+	h2.fields = append(h2.fields, nil)
+
+	return h2
+}
+
+func (h *SlogHandler) clone() *SlogHandler {
+	fields := make([][]Field, len(h.fields))
+	for i := range h.fields {
+		fields[i] = h.fields[i][:len(h.fields[i]):len(h.fields[i])]
+	}
+
+	return &SlogHandler{
+		groups: h.groups[:len(h.groups):len(h.groups)],
+		fields: fields,
+	}
+}
+
+// appendAttr converts a slog.Attr to a Field and appends it to fields.
+// Empty attributes and empty groups are dropped, and groups with an
+// empty key are inlined, following the rules for slog.Handler.
+func appendAttr(fields []Field, a slog.Attr) []Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	if a.Value.Kind() != slog.KindGroup {
+		return append(fields, Field{Key: a.Key, Value: a.Value.Any()})
+	}
+
+	var g []Field
+	for _, ga := range a.Value.Group() {
+		g = appendAttr(g, ga)
+	}
+	if len(g) == 0 {
+		return fields
+	}
+	if a.Key == "" {
+		return append(fields, g...)
+	}
+
+	return append(fields, Field{Key: a.Key, Value: g})
+}
+
+// slogSeverity maps a slog.Level to the logging.Severity used by this package
+func slogSeverity(l slog.Level) logging.Severity {
+	switch {
+	case l < slog.LevelInfo:
+		return logging.Debug
+	case l < slog.LevelWarn:
+		return logging.Info
+	case l < slog.LevelError:
+		return logging.Warning
+	default:
+		return logging.Error
+	}
+}
@@ -0,0 +1,134 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging"
+	"github.com/go-test/deep"
+)
+
+func TestSlogHandler(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		log          func(l *slog.Logger, ctx context.Context)
+		wantSeverity logging.Severity
+		wantPayload  map[string]interface{}
+	}{
+		{
+			name: "message only",
+			log: func(l *slog.Logger, ctx context.Context) {
+				l.InfoContext(ctx, "Message")
+			},
+			wantSeverity: logging.Info,
+			wantPayload: map[string]interface{}{
+				"message": "Message",
+			},
+		},
+		{
+			name: "attributes",
+			log: func(l *slog.Logger, ctx context.Context) {
+				l.WarnContext(ctx, "Message", "order_id", 123, slog.Duration("elapsed", time.Second), "err", errors.New("Bang"))
+			},
+			wantSeverity: logging.Warning,
+			wantPayload: map[string]interface{}{
+				"message":  "Message",
+				"order_id": int64(123),
+				"elapsed":  time.Second,
+				"err":      "Bang",
+			},
+		},
+		{
+			name: "WithAttrs and WithGroup",
+			log: func(l *slog.Logger, ctx context.Context) {
+				l.With("tenant", "acme").WithGroup("order").With("id", 7).WithGroup("item").ErrorContext(ctx, "Message", "sku", "abc")
+			},
+			wantSeverity: logging.Error,
+			wantPayload: map[string]interface{}{
+				"message": "Message",
+				"tenant":  "acme",
+				"order": map[string]interface{}{
+					"id": int64(7),
+					"item": map[string]interface{}{
+						"sku": "abc",
+					},
+				},
+			},
+		},
+		{
+			name: "empty group dropped",
+			log: func(l *slog.Logger, ctx context.Context) {
+				l.WithGroup("order").DebugContext(ctx, "Message", slog.Group("empty"))
+			},
+			wantSeverity: logging.Debug,
+			wantPayload: map[string]interface{}{
+				"message": "Message",
+			},
+		},
+		{
+			name: "inline group",
+			log: func(l *slog.Logger, ctx context.Context) {
+				l.InfoContext(ctx, "Message", slog.Group("", "a", 1), slog.Group("g", "b", 2))
+			},
+			wantSeverity: logging.Info,
+			wantPayload: map[string]interface{}{
+				"message": "Message",
+				"a":       int64(1),
+				"g": map[string]interface{}{
+					"b": int64(2),
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := &captureLogger{}
+			ctx := newContext(context.Background(), newRequestLogger(&gcpSink{childLogger: c}, &http.Request{}, ""))
+
+			tt.log(slog.New(NewSlogHandler()), ctx)
+
+			if c.e.Severity != tt.wantSeverity {
+				t.Errorf("Severity = %v, want %v", c.e.Severity, tt.wantSeverity)
+			}
+			if diff := deep.Equal(c.e.Payload, tt.wantPayload); diff != nil {
+				t.Errorf("Payload = %v", diff)
+			}
+		})
+	}
+}
+
+func Test_slogSeverity(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		level slog.Level
+		want  logging.Severity
+	}{
+		{name: "below debug", level: slog.LevelDebug - 4, want: logging.Debug},
+		{name: "debug", level: slog.LevelDebug, want: logging.Debug},
+		{name: "info", level: slog.LevelInfo, want: logging.Info},
+		{name: "info+2", level: slog.LevelInfo + 2, want: logging.Info},
+		{name: "warn", level: slog.LevelWarn, want: logging.Warning},
+		{name: "error", level: slog.LevelError, want: logging.Error},
+		{name: "above error", level: slog.LevelError + 4, want: logging.Error},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := slogSeverity(tt.level); got != tt.want {
+				t.Errorf("slogSeverity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
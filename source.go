@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// maxStackDepth is the maximum number of frames in a stack trace captured by callerStack
+const maxStackDepth = 64
+
+// callerSkip is the number of frames between runtime.Callers and the caller of a Logger method
+const callerSkip = 3
+
+// source returns the source location of the program counter pc, or nil if pc is 0
+func source(pc uintptr) *slog.Source {
+	if pc == 0 {
+		return nil
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+
+	return &slog.Source{
+		Function: frame.Function,
+		File:     frame.File,
+		Line:     frame.Line,
+	}
+}
+
+// formatSource returns " file.go:line" for s, or an empty string if s is nil
+func formatSource(s *slog.Source) string {
+	if s == nil {
+		return ""
+	}
+
+	return " " + filepath.Base(s.File) + ":" + strconv.Itoa(s.Line)
+}
+
+// callerStack returns the stack trace of the goroutine, starting at the frame of the program counter pc.
+// It is formatted like runtime/debug.Stack, so it is parsed by Cloud Error Reporting.
+func callerStack(pc uintptr) string {
+	pcs := make([]uintptr, maxStackDepth)
+	pcs = pcs[:runtime.Callers(2, pcs)]
+	for i := range pcs {
+		if pcs[i] == pc {
+			pcs = pcs[i:]
+
+			break
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("goroutine 1 [running]:\n")
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "%s(...)\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+
+	return b.String()
+}
@@ -5,7 +5,10 @@ import (
 	"context"
 	"log"
 	"os"
+	"regexp"
 	"testing"
+
+	"cloud.google.com/go/logging"
 )
 
 func Test_stdErrLogger(t *testing.T) {
@@ -47,58 +50,123 @@ func Test_stdErrLogger(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ctx := context.Background()
-
-			l := &stdErrLogger{}
+			l := Ctx(context.Background())
 			format := "Formatted %s"
 
-			l.Debug(ctx, tt.args.v2)
-			if s := buf.String()[20:]; s != tt.wantDebug {
+			l.Debug(tt.args.v2)
+			if s := trimSource(buf.String()[20:]); s != tt.wantDebug {
 				t.Errorf("stdErrLogger.Debug() value = %v, wantValue %v", s, tt.wantDebug)
 			}
 			buf.Reset()
 
-			l.Debugf(ctx, format, tt.args.v...)
-			if s := buf.String()[20:]; s != tt.wantDebugf {
+			l.Debugf(format, tt.args.v...)
+			if s := trimSource(buf.String()[20:]); s != tt.wantDebugf {
 				t.Errorf("stdErrLogger.Debug() value = %v, wantValue %v", s, tt.wantDebugf)
 			}
 			buf.Reset()
 
-			l.Info(ctx, tt.args.v2)
-			if s := buf.String()[20:]; s != tt.wantInfo {
+			l.Info(tt.args.v2)
+			if s := trimSource(buf.String()[20:]); s != tt.wantInfo {
 				t.Errorf("stdErrLogger.Info() value = %v, wantValue %v", s, tt.wantInfo)
 			}
 			buf.Reset()
 
-			l.Infof(ctx, format, tt.args.v...)
-			if s := buf.String()[20:]; s != tt.wantInfof {
+			l.Infof(format, tt.args.v...)
+			if s := trimSource(buf.String()[20:]); s != tt.wantInfof {
 				t.Errorf("stdErrLogger.Info() value = %v, wantValue %v", s, tt.wantInfof)
 			}
 			buf.Reset()
 
-			l.Warn(ctx, tt.args.v2)
-			if s := buf.String()[20:]; s != tt.wantWarn {
+			l.Warn(tt.args.v2)
+			if s := trimSource(buf.String()[20:]); s != tt.wantWarn {
 				t.Errorf("stdErrLogger.Warn() value = %v, wantValue %v", s, tt.wantWarn)
 			}
 			buf.Reset()
 
-			l.Warnf(ctx, format, tt.args.v...)
-			if s := buf.String()[20:]; s != tt.wantWarnf {
+			l.Warnf(format, tt.args.v...)
+			if s := trimSource(buf.String()[20:]); s != tt.wantWarnf {
 				t.Errorf("stdErrLogger.Warn() value = %v, wantValue %v", s, tt.wantWarnf)
 			}
 			buf.Reset()
 
-			l.Error(ctx, tt.args.v2)
-			if s := buf.String()[20:]; s != tt.wantError {
+			l.Error(tt.args.v2)
+			if s := trimSource(buf.String()[20:]); s != tt.wantError {
 				t.Errorf("stdErrLogger.Error() value = %v, wantValue %v", s, tt.wantError)
 			}
 			buf.Reset()
 
-			l.Errorf(ctx, format, tt.args.v...)
-			if s := buf.String()[20:]; s != tt.wantErrorf {
+			l.Errorf(format, tt.args.v...)
+			if s := trimSource(buf.String()[20:]); s != tt.wantErrorf {
 				t.Errorf("stdErrLogger.Error() value = %v, wantValue %v", s, tt.wantErrorf)
 			}
 			buf.Reset()
 		})
 	}
 }
+
+func Test_stdErrLogger_Default(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	Ctx(context.Background()).Log(logging.Default, "Message")
+	if s := trimSource(buf.String()[20:]); s != "LOG  : Message\n" {
+		t.Errorf("stdErrLogger.Log() value = %v, wantValue %v", s, "LOG  : Message\n")
+	}
+}
+
+func Test_stdErrLogger_fields(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	ctx := context.Background()
+	fields := []Field{{Key: "order_id", Value: 123}, {Key: "tenant", Value: "acme"}}
+	l := &stdErrLogger{}
+
+	tests := []struct {
+		name     string
+		severity logging.Severity
+		want     string
+	}{
+		{name: "Debug", severity: logging.Debug, want: "DEBUG: Message order_id=123 tenant=acme\n"},
+		{name: "Info", severity: logging.Info, want: "INFO : Message order_id=123 tenant=acme\n"},
+		{name: "Warning", severity: logging.Warning, want: "WARN : Message order_id=123 tenant=acme\n"},
+		{name: "Notice", severity: logging.Notice, want: "NOTE : Message order_id=123 tenant=acme\n"},
+		{name: "Error", severity: logging.Error, want: "ERROR: Message order_id=123 tenant=acme\n"},
+		{name: "Critical", severity: logging.Critical, want: "CRIT : Message order_id=123 tenant=acme\n"},
+		{name: "Alert", severity: logging.Alert, want: "ALERT: Message order_id=123 tenant=acme\n"},
+		{name: "Emergency", severity: logging.Emergency, want: "EMERG: Message order_id=123 tenant=acme\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			l.Log(ctx, tt.severity, "Message", fields, 0)
+			if s := buf.String()[20:]; s != tt.want {
+				t.Errorf("stdErrLogger.Log() value = %v, wantValue %v", s, tt.want)
+			}
+		})
+	}
+}
+
+func Test_stdErrLogger_source(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	Ctx(context.Background()).AddCallerSkip(1).Info("Message")
+	if s := buf.String()[20:]; !regexp.MustCompile(`^INFO : Message testing\.go:\d+\n$`).MatchString(s) {
+		t.Errorf("Logger.AddCallerSkip() value = %v, want the caller of the test", s)
+	}
+	buf.Reset()
+
+	Ctx(context.Background()).Info("Message")
+	if s := buf.String()[20:]; !regexp.MustCompile(`^INFO : Message std_test\.go:\d+\n$`).MatchString(s) {
+		t.Errorf("Logger.Info() value = %v, want the source location of the test", s)
+	}
+}
+
+// trimSource removes the source location of a log written from this file
+func trimSource(s string) string {
+	return regexp.MustCompile(` std_test\.go:\d+\n$`).ReplaceAllString(s, "\n")
+}